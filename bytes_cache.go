@@ -8,6 +8,11 @@ import (
 	"unsafe"
 )
 
+// Coster computes the charge (e.g. byte size) that a key/value pair accounts
+// for against a capacity-bounded BytesCache. It is only consulted by caches
+// created with NewBytesCacheWithCapacity.
+type Coster func(key []byte, value []byte) uint32
+
 // BytesCache implements Bytes Cache with least recent used eviction policy.
 type BytesCache struct {
 	shards [512]bytesshard
@@ -16,6 +21,7 @@ type BytesCache struct {
 	seed   uintptr
 	loader func(ctx context.Context, key []byte) (value []byte, err error)
 	group  singleflight_Group[string, []byte]
+	coster Coster
 }
 
 // NewBytesCache creates bytes cache with size capacity.
@@ -50,6 +56,39 @@ func NewBytesCache[K comparable, V any](size int) *BytesCache {
 	return c
 }
 
+// NewBytesCacheWithPolicy creates a bytes cache with size capacity using the
+// given eviction policy (e.g. NewBytesCacheWithPolicy(size, PolicySIEVE)).
+func NewBytesCacheWithPolicy[K comparable, V any](size int, policy EvictionPolicy) *BytesCache {
+	c := NewBytesCache[K, V](size)
+	for i := uint32(0); i <= c.mask; i++ {
+		c.shards[i].SetPolicy(policy)
+	}
+	return c
+}
+
+// NewBytesCacheWithCapacity creates a bytes cache bounded by a total byte
+// budget instead of a fixed node count. Each Set computes a per-entry charge
+// via coster (defaulting to len(key)+len(value) when coster is nil) and
+// eviction walks the LRU tail of each shard until total charge fits.
+func NewBytesCacheWithCapacity(bytes int64, coster Coster) *BytesCache {
+	// size the node rings generously since entries vary in cost; shards
+	// reclaim slots by charge, not by count, once capacity is reached.
+	c := NewBytesCache[string, []byte](int(bytes))
+	if coster == nil {
+		coster = func(key []byte, value []byte) uint32 {
+			return uint32(len(key) + len(value))
+		}
+	}
+	c.coster = coster
+
+	shardCapacity := uint32(bytes / int64(c.mask+1))
+	for i := uint32(0); i <= c.mask; i++ {
+		c.shards[i].SetCapacity(shardCapacity)
+	}
+
+	return c
+}
+
 // Get returns value for key.
 func (c *BytesCache) Get(key []byte) (value []byte, ok bool) {
 	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
@@ -57,6 +96,20 @@ func (c *BytesCache) Get(key []byte) (value []byte, ok bool) {
 	return (*bytesshard)(unsafe.Add(unsafe.Pointer(&c.shards[0]), uintptr(hash&c.mask)*unsafe.Sizeof(c.shards[0]))).Get(hash, key)
 }
 
+// GetHandle returns a reference-counted handle onto the value for key,
+// pinning it so a concurrent Set cannot recycle the underlying slot. Callers
+// must call Release on the returned handle once done with it. This lets
+// callers stream out a []byte value without copying it.
+func (c *BytesCache) GetHandle(key []byte) (*Handle[[]byte], bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	shard := (*bytesshard)(unsafe.Add(unsafe.Pointer(&c.shards[0]), uintptr(hash&c.mask)*unsafe.Sizeof(c.shards[0])))
+	index, value, ok := shard.GetHandle(hash, key)
+	if !ok {
+		return nil, false
+	}
+	return newHandle(value, func() { shard.releaseHandle(index) }), true
+}
+
 // GetOrLoad returns value for key, call loader function by singleflight if value was not in cache.
 func (c *BytesCache) GetOrLoad(ctx context.Context, key []byte, loader func(context.Context, []byte) ([]byte, error)) (value []byte, err error, ok bool) {
 	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
@@ -74,7 +127,11 @@ func (c *BytesCache) GetOrLoad(ctx context.Context, key []byte, loader func(cont
 			if err != nil {
 				return v, err
 			}
-			c.shards[hash&c.mask].Set(hash, key, v)
+			var charge uint32
+			if c.coster != nil {
+				charge = c.coster(key, v)
+			}
+			c.shards[hash&c.mask].Set(hash, key, v, charge)
 			return v, nil
 		})
 	}
@@ -91,15 +148,23 @@ func (c *BytesCache) Peek(key []byte) (value []byte, ok bool) {
 // Set inserts key value pair and returns previous value.
 func (c *BytesCache) Set(key []byte, value []byte) (prev []byte, replaced bool) {
 	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
-	// return c.shards[hash&c.mask].Set(hash, key, value)
-	return (*bytesshard)(unsafe.Add(unsafe.Pointer(&c.shards[0]), uintptr(hash&c.mask)*unsafe.Sizeof(c.shards[0]))).Set(hash, key, value)
+	var charge uint32
+	if c.coster != nil {
+		charge = c.coster(key, value)
+	}
+	// return c.shards[hash&c.mask].Set(hash, key, value, charge)
+	return (*bytesshard)(unsafe.Add(unsafe.Pointer(&c.shards[0]), uintptr(hash&c.mask)*unsafe.Sizeof(c.shards[0]))).Set(hash, key, value, charge)
 }
 
 // SetIfAbsent inserts key value pair and returns previous value, if key is absent in the cache.
 func (c *BytesCache) SetIfAbsent(key []byte, value []byte) (prev []byte, replaced bool) {
 	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
-	// return c.shards[hash&c.mask].SetIfAbsent(hash, key, value)
-	return (*bytesshard)(unsafe.Add(unsafe.Pointer(&c.shards[0]), uintptr(hash&c.mask)*unsafe.Sizeof(c.shards[0]))).SetIfAbsent(hash, key, value)
+	var charge uint32
+	if c.coster != nil {
+		charge = c.coster(key, value)
+	}
+	// return c.shards[hash&c.mask].SetIfAbsent(hash, key, value, charge)
+	return (*bytesshard)(unsafe.Add(unsafe.Pointer(&c.shards[0]), uintptr(hash&c.mask)*unsafe.Sizeof(c.shards[0]))).SetIfAbsent(hash, key, value, charge)
 }
 
 // Delete method deletes value associated with key and returns deleted value (or empty value if key was not in cache).
@@ -135,6 +200,8 @@ func (c *BytesCache) Stats() (stats Stats) {
 		stats.GetCalls += s.stats_getcalls
 		stats.SetCalls += s.stats_setcalls
 		stats.Misses += s.stats_misses
+		stats.Bytes += uint64(s.used)
+		stats.Capacity += uint64(s.capacity)
 		s.mu.Unlock()
 	}
 	return
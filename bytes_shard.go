@@ -0,0 +1,489 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// bytesnode is a list of bytes node, storing key-value pairs and related information
+type bytesnode struct {
+	key     []byte
+	next    uint32
+	prev    uint32
+	charge  uint32
+	ns      uint32
+	refs    int32
+	pending bool
+	visited bool
+	value   []byte
+}
+
+type bytesbucket struct {
+	hdib  uint32 // bitfield { hash:24 dib:8 }
+	index uint32 // node index
+}
+
+// bytesshard is a LRU partition contains a list and a hash table.
+type bytesshard struct {
+	mu sync.Mutex
+
+	// the hash table, with 20% extra space than the list for fewer conflicts.
+	table_buckets []uint64 // []bytesbucket
+	table_mask    uint32
+	table_length  uint32
+	table_hasher  func(key unsafe.Pointer, seed uintptr) uintptr
+	table_seed    uintptr
+
+	// the list of nodes
+	list []bytesnode
+
+	// eviction policy, defaults to PolicyLRU; PolicySIEVE walks hand instead
+	// of evicting the strict list tail and skips the move-to-front on Get.
+	policy EvictionPolicy
+	hand   uint32
+
+	// charge-based capacity accounting, 0 means node-count based (unlimited bytes)
+	capacity uint32
+	used     uint32
+
+	// stats
+	stats_getcalls uint64
+	stats_setcalls uint64
+	stats_misses   uint64
+}
+
+func (s *bytesshard) Init(size uint32, hasher func(key unsafe.Pointer, seed uintptr) uintptr, seed uintptr) {
+	s.list_Init(size)
+	s.table_Init(size, hasher, seed)
+}
+
+// SetCapacity sets the shard's byte/cost budget. A capacity of 0 disables
+// charge-based eviction and falls back to the fixed node-count ring.
+func (s *bytesshard) SetCapacity(capacity uint32) {
+	s.mu.Lock()
+	s.capacity = capacity
+	s.mu.Unlock()
+}
+
+// SetPolicy selects the shard's eviction policy. Callers should do this
+// right after Init, before the shard sees any traffic.
+func (s *bytesshard) SetPolicy(policy EvictionPolicy) {
+	s.mu.Lock()
+	s.policy = policy
+	s.hand = s.list[0].prev
+	s.mu.Unlock()
+}
+
+// sieveCandidate walks the SIEVE hand backward from its current position,
+// clearing visited bits, until it lands on an unvisited node to evict.
+// Callers must hold s.mu.
+func (s *bytesshard) sieveCandidate() uint32 {
+	if s.hand == 0 {
+		s.hand = s.list[0].prev
+	}
+	for {
+		if s.hand == 0 {
+			s.hand = s.list[0].prev
+			continue
+		}
+		node := &s.list[s.hand]
+		if node.visited {
+			node.visited = false
+			s.hand = node.prev
+			continue
+		}
+		break
+	}
+	return s.hand
+}
+
+// nextEvictable returns the index of the next node the active policy wants
+// to reclaim, skipping any node currently checked out via GetHandle. Pinned
+// nodes stay live and are pushed to the front, out of the way of the next
+// eviction attempt. The scan is
+// bounded by table_length: if every live node is pinned, ok is false rather
+// than spinning forever under s.mu. Callers must hold s.mu.
+func (s *bytesshard) nextEvictable() (index uint32, ok bool) {
+	candidate := func() uint32 {
+		if s.policy == PolicySIEVE {
+			return s.sieveCandidate()
+		}
+		return s.list[0].prev
+	}
+
+	index = candidate()
+	for tries := uint32(0); s.list[index].refs > 0; tries++ {
+		if tries >= s.table_length {
+			return 0, false
+		}
+		s.list_MoveToFront(index)
+		if s.policy == PolicySIEVE {
+			s.hand = s.list[0].prev
+		}
+		index = candidate()
+	}
+	if s.policy == PolicySIEVE {
+		s.hand = s.list[index].prev
+	}
+	return index, true
+}
+
+// evictForCharge evicts entries from the list tail, removing them from the
+// table, until there is enough room for charge more, the shard is empty, or
+// every remaining entry is pinned (in which case the shard is left to
+// temporarily exceed capacity rather than block). Callers must hold s.mu.
+func (s *bytesshard) evictForCharge(charge uint32) {
+	if s.capacity == 0 {
+		return
+	}
+	for s.table_length > 0 && s.used+charge > s.capacity {
+		// free slots sit at the back of the list: walk from the tail toward
+		// the front to the least recently used live, unpinned entry.
+		index := s.list[0].prev
+		for ; index != 0; index = s.list[index].prev {
+			node := &s.list[index]
+			if i, ok := s.table_Get(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key); ok && i == index && node.refs == 0 {
+				break
+			}
+		}
+		if index == 0 {
+			return
+		}
+		node := &s.list[index]
+		s.used -= node.charge
+		s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+		node.value = nil
+		node.charge = 0
+		s.list_MoveToBack(index)
+	}
+}
+
+// touch records a successful Get against index per the active policy: under
+// PolicyLRU it moves the node to the front of the list; under PolicySIEVE it
+// only flips the visited bit, leaving list order (and hence the hot-path
+// write) untouched.
+func (s *bytesshard) touch(index uint32) {
+	if s.policy == PolicySIEVE {
+		s.list[index].visited = true
+		return
+	}
+	s.list_MoveToFront(index)
+}
+
+func (s *bytesshard) Get(hash uint32, key []byte) (value []byte, ok bool) {
+	s.mu.Lock()
+
+	s.stats_getcalls++
+
+	if index, exists := s.table_Get(hash, key); exists {
+		s.touch(index)
+		value = s.list[index].value
+		ok = true
+	} else {
+		s.stats_misses++
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+func (s *bytesshard) Peek(hash uint32, key []byte) (value []byte, ok bool) {
+	s.mu.Lock()
+
+	if index, exists := s.table_Get(hash, key); exists {
+		value = s.list[index].value
+		ok = true
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+// GetHandle is like Get but pins the entry in place: it will not be reused
+// by eviction until the returned index is passed to releaseHandle, even if
+// it is overwritten in the meantime.
+func (s *bytesshard) GetHandle(hash uint32, key []byte) (index uint32, value []byte, ok bool) {
+	s.mu.Lock()
+
+	if idx, exists := s.table_Get(hash, key); exists {
+		node := &s.list[idx]
+		node.refs++
+		s.list_MoveToFront(idx)
+		index, value, ok = idx, node.value, true
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+// releaseHandle drops a reference taken by GetHandle. If the node was
+// deleted or evicted by EvictNS while checked out, releasing the last
+// reference recycles its slot: the table entry for its key is already gone
+// (Delete and EvictNS remove it up front), so it only remains to drop the value and
+// send the node to the back of the list for reuse.
+func (s *bytesshard) releaseHandle(index uint32) {
+	s.mu.Lock()
+
+	node := &s.list[index]
+	node.refs--
+	if node.refs == 0 && node.pending {
+		node.pending = false
+		node.value = nil
+		node.charge = 0
+		s.list_MoveToBack(index)
+	}
+
+	s.mu.Unlock()
+}
+
+// SetIfAbsent inserts key value pair, if key is absent in the cache. ns is
+// optional (defaulting to 0, the default namespace) so existing callers that
+// never opted into namespaces keep a 4-argument call site.
+func (s *bytesshard) SetIfAbsent(hash uint32, key []byte, value []byte, charge uint32, ns ...uint32) (prev []byte, replaced bool) {
+	var n uint32
+	if len(ns) > 0 {
+		n = ns[0]
+	}
+
+	s.mu.Lock()
+
+	if index, exists := s.table_Get(hash, key); exists {
+		prev = s.list[index].value
+		s.mu.Unlock()
+		return
+	}
+
+	s.stats_setcalls++
+
+	s.evictForCharge(charge)
+
+	index, ok := s.nextEvictable()
+	if !ok {
+		// Every node in the shard is pinned via GetHandle: there is no slot
+		// to reclaim right now. Drop the Set rather than spin or corrupt a
+		// pinned handle's value.
+		s.mu.Unlock()
+		return
+	}
+	node := &s.list[index]
+	evictedValue := node.value
+	s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+	if s.capacity > 0 {
+		s.used = s.used - node.charge + charge
+	}
+
+	node.key = key
+	node.value = value
+	node.charge = charge
+	node.ns = n
+	s.table_Set(hash, key, index)
+	s.list_MoveToFront(index)
+	prev = evictedValue
+
+	s.mu.Unlock()
+	return
+}
+
+// Set inserts key value pair and returns previous value. ns is optional, see
+// SetIfAbsent.
+func (s *bytesshard) Set(hash uint32, key []byte, value []byte, charge uint32, ns ...uint32) (prev []byte, replaced bool) {
+	var n uint32
+	if len(ns) > 0 {
+		n = ns[0]
+	}
+
+	s.mu.Lock()
+
+	s.stats_setcalls++
+
+	if index, exists := s.table_Get(hash, key); exists {
+		node := &s.list[index]
+		previousValue := node.value
+		s.list_MoveToFront(index)
+		node.value = value
+		if s.capacity > 0 {
+			s.used = s.used - node.charge + charge
+		}
+		node.charge = charge
+		node.ns = n
+		prev = previousValue
+		replaced = true
+
+		s.mu.Unlock()
+		return
+	}
+
+	s.evictForCharge(charge)
+
+	index, ok := s.nextEvictable()
+	if !ok {
+		// Every node in the shard is pinned via GetHandle: there is no slot
+		// to reclaim right now. Drop the Set rather than spin or corrupt a
+		// pinned handle's value.
+		s.mu.Unlock()
+		return
+	}
+	node := &s.list[index]
+	evictedValue := node.value
+	if !bytesEqual(key, node.key) {
+		s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+	}
+	if s.capacity > 0 {
+		s.used = s.used - node.charge + charge
+	}
+
+	node.key = key
+	node.value = value
+	node.charge = charge
+	node.ns = n
+	s.table_Set(hash, key, index)
+	s.list_MoveToFront(index)
+	prev = evictedValue
+
+	s.mu.Unlock()
+	return
+}
+
+func (s *bytesshard) Delete(hash uint32, key []byte) (v []byte) {
+	s.mu.Lock()
+
+	if index, exists := s.table_Get(hash, key); exists {
+		node := &s.list[index]
+		value := node.value
+
+		s.table_Delete(hash, key)
+		if s.capacity > 0 {
+			s.used -= node.charge
+		}
+
+		if node.refs > 0 {
+			// Pinned via GetHandle: defer clobbering the value and recycling
+			// the slot to releaseHandle.
+			node.pending = true
+		} else {
+			s.list_MoveToBack(index)
+			node.value = nil
+			node.charge = 0
+		}
+		v = value
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+func (s *bytesshard) Len() (n uint32) {
+	s.mu.Lock()
+	// inlining s.table_Len()
+	n = s.table_length
+	s.mu.Unlock()
+
+	return
+}
+
+func (s *bytesshard) AppendKeys(dst [][]byte) [][]byte {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*bytesbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		dst = append(dst, s.list[b.index].key)
+	}
+	s.mu.Unlock()
+
+	return dst
+}
+
+// LenNS returns the number of live entries tagged with namespace ns.
+func (s *bytesshard) LenNS(ns uint32) (n uint32) {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*bytesbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		if s.list[b.index].ns == ns {
+			n++
+		}
+	}
+	s.mu.Unlock()
+
+	return
+}
+
+// AppendKeysNS is like AppendKeys but restricted to entries tagged with ns.
+func (s *bytesshard) AppendKeysNS(dst [][]byte, ns uint32) [][]byte {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*bytesbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		node := &s.list[b.index]
+		if node.ns != ns {
+			continue
+		}
+		dst = append(dst, node.key)
+	}
+	s.mu.Unlock()
+
+	return dst
+}
+
+// EvictNS drops every entry tagged with namespace ns and returns the count
+// evicted.
+func (s *bytesshard) EvictNS(ns uint32) (n uint32) {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*bytesbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		node := &s.list[b.index]
+		if node.ns != ns {
+			continue
+		}
+		if s.capacity > 0 {
+			s.used -= node.charge
+		}
+		hash := uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed))
+		// entries set through a Namespace handle are keyed by the mixed hash
+		if i, ok := s.table_Get(mixNamespace(hash, ns), node.key); ok && i == b.index {
+			hash = mixNamespace(hash, ns)
+		}
+		s.table_Delete(hash, node.key)
+		if node.refs > 0 {
+			// pinned via GetHandle: releaseHandle recycles the slot
+			node.pending = true
+		} else {
+			s.list_MoveToBack(b.index)
+			node.value = nil
+			node.charge = 0
+		}
+		n++
+	}
+	s.mu.Unlock()
+
+	return
+}
+
+// bytesEqual reports whether two byte slices have the same contents; used in
+// place of bytes.Equal to avoid pulling in the bytes package for one check.
+func bytesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "testing"
+
+func TestBytesShardChargeEviction(t *testing.T) {
+	var s bytesshard
+	s.Init(4, getRuntimeHasher[string](), 0)
+	s.SetCapacity(3)
+
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+	s.Set(bytesShardHash(&s, a), a, []byte("1"), 1)
+	s.Set(bytesShardHash(&s, b), b, []byte("22"), 2)
+	if got := s.used; got != 3 {
+		t.Fatalf("used = %d, want 3", got)
+	}
+
+	// "c" needs 1 more than the remaining budget: the LRU tail ("a") must be
+	// evicted to make room before "c" is inserted.
+	s.Set(bytesShardHash(&s, c), c, []byte("3"), 1)
+	if got := s.used; got != 3 {
+		t.Fatalf("used after charge eviction = %d, want 3", got)
+	}
+	if _, ok := s.table_Get(bytesShardHash(&s, a), a); ok {
+		t.Fatalf("\"a\" should have been evicted to make room for \"c\"")
+	}
+	if _, ok := s.table_Get(bytesShardHash(&s, b), b); !ok {
+		t.Fatalf("\"b\" should still be present")
+	}
+}
+
+func TestBytesShardDeleteReleasesCharge(t *testing.T) {
+	var s bytesshard
+	s.Init(4, getRuntimeHasher[string](), 0)
+	s.SetCapacity(10)
+
+	a := []byte("a")
+	hash := bytesShardHash(&s, a)
+	s.Set(hash, a, []byte("1234"), 4)
+	if got := s.used; got != 4 {
+		t.Fatalf("used = %d, want 4", got)
+	}
+
+	s.Delete(hash, a)
+	if got := s.used; got != 0 {
+		t.Fatalf("used after Delete = %d, want 0", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func bytesShardHash(s *bytesshard, key []byte) uint32 {
+	return uint32(s.table_hasher(noescape(unsafe.Pointer(&key)), s.table_seed))
+}
+
+func TestBytesShardHandlePinsAgainstEviction(t *testing.T) {
+	var s bytesshard
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	a := []byte("a")
+	ha := bytesShardHash(&s, a)
+	s.Set(ha, a, []byte("1"), 0)
+
+	index, value, ok := s.GetHandle(ha, a)
+	if !ok || string(value) != "1" {
+		t.Fatalf("GetHandle(a) = %v, %v, %v, want \"1\", true", value, ok, index)
+	}
+
+	// Insert enough other keys to cycle the whole ring; "a" must never be
+	// reused as a victim while its handle is outstanding.
+	b, c := []byte("b"), []byte("c")
+	s.Set(bytesShardHash(&s, b), b, []byte("2"), 0)
+	s.Set(bytesShardHash(&s, c), c, []byte("3"), 0)
+
+	if _, ok := s.table_Get(ha, a); !ok {
+		t.Fatalf("pinned key \"a\" was evicted while its handle was outstanding")
+	}
+
+	s.releaseHandle(index)
+}
+
+func TestBytesShardNextEvictableAllPinned(t *testing.T) {
+	var s bytesshard
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	for _, key := range [][]byte{[]byte("a"), []byte("b")} {
+		hash := bytesShardHash(&s, key)
+		s.Set(hash, key, []byte("1"), 0)
+		if _, _, ok := s.GetHandle(hash, key); !ok {
+			t.Fatalf("GetHandle(%s) failed", key)
+		}
+	}
+
+	if _, ok := s.nextEvictable(); ok {
+		t.Fatalf("nextEvictable() should report no victim when every node is pinned, not pick one")
+	}
+}
+
+func TestBytesShardDeleteRespectsPinnedHandle(t *testing.T) {
+	var s bytesshard
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	a := []byte("a")
+	ha := bytesShardHash(&s, a)
+	s.Set(ha, a, []byte("1"), 0)
+
+	index, _, ok := s.GetHandle(ha, a)
+	if !ok {
+		t.Fatalf("GetHandle(a) failed")
+	}
+
+	if v := s.Delete(ha, a); string(v) != "1" {
+		t.Fatalf("Delete(a) = %v, want \"1\"", v)
+	}
+	if _, ok := s.table_Get(ha, a); ok {
+		t.Fatalf("deleted key \"a\" is still reachable via table_Get")
+	}
+
+	// The handle is still outstanding: the slot must not be recycled yet.
+	if string(s.list[index].value) != "1" {
+		t.Fatalf("Delete finalized the pinned node's slot before its handle was released")
+	}
+
+	s.releaseHandle(index)
+	if s.list[index].value != nil {
+		t.Fatalf("releaseHandle did not finalize a pending Delete once the last reference dropped")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "testing"
+
+func TestBytesShardNamespaceTagging(t *testing.T) {
+	var s bytesshard
+	s.Init(4, getRuntimeHasher[string](), 0)
+
+	a, b := []byte("a"), []byte("b")
+	s.Set(bytesShardHash(&s, a), a, []byte("1"), 0, 7)
+	s.Set(bytesShardHash(&s, b), b, []byte("2"), 0, 9)
+
+	if got := s.LenNS(7); got != 1 {
+		t.Fatalf("LenNS(7) = %d, want 1", got)
+	}
+	if got := s.LenNS(9); got != 1 {
+		t.Fatalf("LenNS(9) = %d, want 1", got)
+	}
+
+	keys := s.AppendKeysNS(nil, 7)
+	if len(keys) != 1 || !bytesEqual(keys[0], a) {
+		t.Fatalf("AppendKeysNS(7) = %v, want [a]", keys)
+	}
+
+	if n := s.EvictNS(7); n != 1 {
+		t.Fatalf("EvictNS(7) = %d, want 1", n)
+	}
+	if _, ok := s.table_Get(bytesShardHash(&s, a), a); ok {
+		t.Fatalf("\"a\" should have been evicted by EvictNS(7)")
+	}
+	if _, ok := s.table_Get(bytesShardHash(&s, b), b); !ok {
+		t.Fatalf("\"b\" (namespace 9) should be unaffected by EvictNS(7)")
+	}
+}
+
+func TestBytesShardSetOverwriteUpdatesNamespace(t *testing.T) {
+	var s bytesshard
+	s.Init(4, getRuntimeHasher[string](), 0)
+
+	a := []byte("a")
+	hash := bytesShardHash(&s, a)
+	s.Set(hash, a, []byte("1"), 0, 1)
+	s.Set(hash, a, []byte("2"), 0, 2)
+
+	if got := s.LenNS(1); got != 0 {
+		t.Fatalf("LenNS(1) = %d, want 0 after overwrite with namespace 2", got)
+	}
+	if got := s.LenNS(2); got != 1 {
+		t.Fatalf("LenNS(2) = %d, want 1", got)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "testing"
+
+// TestBytesShardSievePreservesVisited mirrors the lrushard SIEVE coverage:
+// PolicySIEVE must skip and clear visited entries, reclaiming only the lone
+// node nobody touched since it was inserted.
+func TestBytesShardSievePreservesVisited(t *testing.T) {
+	var s bytesshard
+	s.Init(3, getRuntimeHasher[string](), 0)
+	s.SetPolicy(PolicySIEVE)
+
+	a, b, c, d := []byte("a"), []byte("b"), []byte("c"), []byte("d")
+	s.Set(bytesShardHash(&s, a), a, []byte("1"), 0)
+	s.Set(bytesShardHash(&s, b), b, []byte("2"), 0)
+	s.Set(bytesShardHash(&s, c), c, []byte("3"), 0)
+
+	s.Get(bytesShardHash(&s, a), a)
+	s.Get(bytesShardHash(&s, b), b)
+
+	s.Set(bytesShardHash(&s, d), d, []byte("4"), 0)
+
+	if _, ok := s.table_Get(bytesShardHash(&s, c), c); ok {
+		t.Fatalf("\"c\" is the only unvisited entry and should have been evicted")
+	}
+	if _, ok := s.table_Get(bytesShardHash(&s, a), a); !ok {
+		t.Fatalf("visited entry \"a\" should have survived eviction")
+	}
+	if _, ok := s.table_Get(bytesShardHash(&s, b), b); !ok {
+		t.Fatalf("visited entry \"b\" should have survived eviction")
+	}
+}
+
+func TestNewBytesCacheWithPolicySetsSieve(t *testing.T) {
+	c := NewBytesCacheWithPolicy[string, []byte](8, PolicySIEVE)
+	if c.shards[0].policy != PolicySIEVE {
+		t.Fatalf("NewBytesCacheWithPolicy did not set PolicySIEVE on shard 0")
+	}
+}
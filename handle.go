@@ -0,0 +1,33 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "sync/atomic"
+
+// Handle is a reference-counted view onto a value obtained via GetHandle.
+// While held, the entry is pinned in its shard's list/table and cannot be
+// recycled by a concurrent Set or eviction, making it safe to read Value()
+// without copying until Release is called. Call Release once when done;
+// further reads of Value after Release are not safe.
+type Handle[V any] struct {
+	value   V
+	release func()
+	done    int32
+}
+
+func newHandle[V any](value V, release func()) *Handle[V] {
+	return &Handle[V]{value: value, release: release}
+}
+
+// Value returns the pinned value.
+func (h *Handle[V]) Value() V {
+	return h.value
+}
+
+// Release unpins the entry, allowing it to be evicted again. Only the first
+// call has an effect; later calls are no-ops.
+func (h *Handle[V]) Release() {
+	if atomic.CompareAndSwapInt32(&h.done, 0, 1) {
+		h.release()
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "sync"
+
+// Indexer computes a secondary key for a cached value.
+type Indexer[K comparable, V any] func(value V) K
+
+// IndexedCache is a compact in-memory object cache built on top of
+// LRUCache: values are stored once under a primary key, plus N named
+// secondary indexes that resolve to the same primary key, so a row/entity
+// can be looked up by id or by any other unique field (e.g. email, slug).
+type IndexedCache[K comparable, V any] struct {
+	mu         sync.Mutex
+	primary    *LRUCache[K, V]
+	primaryKey Indexer[K, V]
+	indexers   map[string]Indexer[K, V]
+	indexes    map[string]*LRUCache[K, K] // secondary key -> primary key
+	// secKeys remembers, per primary key, the secondary key last written to
+	// each index so overwrites and deletes can invalidate the stale entry.
+	secKeys map[K]map[string]K
+}
+
+// NewIndexedCache creates an indexed cache of size primary capacity.
+// primaryKey extracts the primary key from a value; indexers maps an index
+// name to a function deriving that index's secondary key from the value.
+func NewIndexedCache[K comparable, V any](size int, primaryKey Indexer[K, V], indexers map[string]Indexer[K, V]) *IndexedCache[K, V] {
+	c := &IndexedCache[K, V]{
+		primary:    NewLRUCache[K, V](size),
+		primaryKey: primaryKey,
+		indexers:   indexers,
+		indexes:    make(map[string]*LRUCache[K, K], len(indexers)),
+		secKeys:    make(map[K]map[string]K),
+	}
+	for name := range indexers {
+		c.indexes[name] = NewLRUCache[K, K](size)
+	}
+
+	// primary is capacity-bounded and can drop a key on its own (normal LRU
+	// eviction), not just through IndexedCache.Delete. Without this hook,
+	// that key's secKeys bookkeeping and secondary index entries would never
+	// be cleaned up and would accumulate without bound as the cache churns.
+	c.primary.setOnEvict(c.onPrimaryEvict)
+
+	return c
+}
+
+// onPrimaryEvict invalidates the secondary index entries for a key primary
+// just dropped on its own, keeping secKeys (and hence the indexes) bounded
+// by primary's own capacity instead of growing forever.
+func (c *IndexedCache[K, V]) onPrimaryEvict(key K, _ V) {
+	c.mu.Lock()
+	keys, ok := c.secKeys[key]
+	if ok {
+		delete(c.secKeys, key)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	for name, secKey := range keys {
+		if index, exists := c.indexes[name]; exists {
+			index.Delete(secKey)
+		}
+	}
+}
+
+// Set inserts v, keyed by its primary key, and refreshes every secondary
+// index. If v replaces an entry whose secondary keys have since changed,
+// the stale secondary entries are invalidated.
+func (c *IndexedCache[K, V]) Set(v V) {
+	pk := c.primaryKey(v)
+
+	c.mu.Lock()
+
+	if old, ok := c.secKeys[pk]; ok {
+		for name, oldKey := range old {
+			if index, exists := c.indexes[name]; exists {
+				if newKey := c.indexers[name](v); newKey != oldKey {
+					index.Delete(oldKey)
+				}
+			}
+		}
+	}
+
+	current := make(map[string]K, len(c.indexers))
+	for name, indexer := range c.indexers {
+		secKey := indexer(v)
+		c.indexes[name].Set(secKey, pk)
+		current[name] = secKey
+	}
+	c.secKeys[pk] = current
+
+	c.mu.Unlock()
+
+	// primary.Set must run with c.mu released: once primary is full (the
+	// steady state for any bounded cache) this evicts a different live key
+	// and synchronously calls back into onPrimaryEvict, which takes c.mu
+	// itself. Calling it while still holding c.mu here would deadlock on
+	// the very first cache-full write.
+	c.primary.Set(pk, v)
+}
+
+// Get returns the value for its primary key.
+func (c *IndexedCache[K, V]) Get(key K) (value V, ok bool) {
+	return c.primary.Get(key)
+}
+
+// GetBy returns the value whose named secondary index maps key to a still
+// cached primary key.
+func (c *IndexedCache[K, V]) GetBy(indexName string, key K) (value V, ok bool) {
+	c.mu.Lock()
+	index, exists := c.indexes[indexName]
+	c.mu.Unlock()
+	if !exists {
+		return value, false
+	}
+
+	pk, ok := index.Get(key)
+	if !ok {
+		return value, false
+	}
+	return c.primary.Get(pk)
+}
+
+// Delete removes the value under its primary key and every secondary index
+// entry pointing at it, returning the deleted value if present.
+func (c *IndexedCache[K, V]) Delete(key K) (prev V) {
+	c.mu.Lock()
+	if keys, ok := c.secKeys[key]; ok {
+		for name, secKey := range keys {
+			if index, exists := c.indexes[name]; exists {
+				index.Delete(secKey)
+			}
+		}
+		delete(c.secKeys, key)
+	}
+	c.mu.Unlock()
+
+	return c.primary.Delete(key)
+}
+
+// Len returns the number of values cached under their primary key.
+func (c *IndexedCache[K, V]) Len() int {
+	return c.primary.Len()
+}
@@ -0,0 +1,66 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "testing"
+
+type indexedCacheUser struct {
+	ID    string
+	Email string
+}
+
+func newTestIndexedCache(size int) *IndexedCache[string, indexedCacheUser] {
+	return NewIndexedCache[string, indexedCacheUser](size, func(u indexedCacheUser) string {
+		return u.ID
+	}, map[string]Indexer[string, indexedCacheUser]{
+		"email": func(u indexedCacheUser) string { return u.Email },
+	})
+}
+
+// TestIndexedCacheSetPastCapacityDoesNotDeadlock fills primary past its
+// capacity, the normal steady state for any bounded cache. primary.Set
+// evicting a different live key synchronously calls back into
+// onPrimaryEvict, which must not try to reacquire c.mu while Set is still
+// holding it.
+func TestIndexedCacheSetPastCapacityDoesNotDeadlock(t *testing.T) {
+	c := newTestIndexedCache(2)
+
+	c.Set(indexedCacheUser{ID: "1", Email: "a@example.com"})
+	c.Set(indexedCacheUser{ID: "2", Email: "b@example.com"})
+	c.Set(indexedCacheUser{ID: "3", Email: "c@example.com"})
+
+	if _, ok := c.Get("3"); !ok {
+		t.Fatalf("Get(3) should hit after Set")
+	}
+}
+
+func TestIndexedCacheEvictionInvalidatesSecondaryIndex(t *testing.T) {
+	c := newTestIndexedCache(2)
+
+	c.Set(indexedCacheUser{ID: "1", Email: "a@example.com"})
+	c.Set(indexedCacheUser{ID: "2", Email: "b@example.com"})
+	// Evicts "1" from primary (node-count ring, capacity 2): its secondary
+	// index entry must be invalidated along with it.
+	c.Set(indexedCacheUser{ID: "3", Email: "c@example.com"})
+
+	if _, ok := c.Get("1"); ok {
+		t.Fatalf("user 1 should have been evicted from primary")
+	}
+	if _, ok := c.GetBy("email", "a@example.com"); ok {
+		t.Fatalf("GetBy(email, a@example.com) should miss once user 1 was evicted from primary")
+	}
+	if u, ok := c.GetBy("email", "c@example.com"); !ok || u.ID != "3" {
+		t.Fatalf("GetBy(email, c@example.com) = %v, %v, want user 3, true", u, ok)
+	}
+}
+
+func TestIndexedCacheDeleteInvalidatesSecondaryIndex(t *testing.T) {
+	c := newTestIndexedCache(4)
+
+	c.Set(indexedCacheUser{ID: "1", Email: "a@example.com"})
+	c.Delete("1")
+
+	if _, ok := c.GetBy("email", "a@example.com"); ok {
+		t.Fatalf("GetBy(email, a@example.com) should miss once user 1 was deleted")
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"context"
+	"unsafe"
+)
+
+// LRUCache implements LRU Cache with least recent used eviction policy.
+type LRUCache[K comparable, V any] struct {
+	shards [512]lrushard[K, V]
+	mask   uint32
+	hasher func(key unsafe.Pointer, seed uintptr) uintptr
+	seed   uintptr
+	loader func(ctx context.Context, key K) (value V, err error)
+	group  singleflight_Group[K, V]
+	coster func(key K, value V) uint32
+
+	// onEvict, if set, is invoked (outside any shard lock) whenever Set or
+	// SetIfAbsent recycles a slot whose last key differs from the new one;
+	// that key may already be gone (an unused slot's zero key, or a key
+	// freed by Delete). evictForCharge's charge-budget evictions do not
+	// invoke it when they happen.
+	onEvict func(key K, value V)
+}
+
+// LRUCacheOption configures an LRUCache at construction time, see NewLRUCache.
+type LRUCacheOption[K comparable, V any] func(*LRUCache[K, V])
+
+// WithLRUPolicy selects the eviction policy used by every shard. Defaults to
+// PolicyLRU.
+func WithLRUPolicy[K comparable, V any](policy EvictionPolicy) LRUCacheOption[K, V] {
+	return func(c *LRUCache[K, V]) {
+		for i := uint32(0); i <= c.mask; i++ {
+			c.shards[i].SetPolicy(policy)
+		}
+	}
+}
+
+// NewLRUCache creates lru cache with size capacity.
+func NewLRUCache[K comparable, V any](size int, options ...LRUCacheOption[K, V]) *LRUCache[K, V] {
+	c := new(LRUCache[K, V])
+
+	if c.hasher == nil {
+		c.hasher = getRuntimeHasher[K]()
+	}
+	if c.seed == 0 {
+		c.seed = uintptr(fastrand64())
+	}
+
+	if isamd64 {
+		// pre-alloc lists and tables for compactness
+		shardsize := (uint32(size) + c.mask) / (c.mask + 1)
+		shardlists := make([]lrunode[K, V], (shardsize+1)*(c.mask+1))
+		tablesize := lruNewTableSize(uint32(shardsize))
+		tablebuckets := make([]uint64, tablesize*(c.mask+1))
+		for i := uint32(0); i <= c.mask; i++ {
+			c.shards[i].list = shardlists[i*(shardsize+1) : (i+1)*(shardsize+1)]
+			c.shards[i].table_buckets = tablebuckets[i*tablesize : (i+1)*tablesize]
+			c.shards[i].Init(shardsize, c.hasher, c.seed)
+		}
+	} else {
+		shardsize := (uint32(size) + c.mask) / (c.mask + 1)
+		for i := uint32(0); i <= c.mask; i++ {
+			c.shards[i].Init(shardsize, c.hasher, c.seed)
+		}
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// NewLRUCacheWithPolicy creates an lru cache with size capacity using the
+// given eviction policy (e.g. NewLRUCacheWithPolicy(size, PolicySIEVE)).
+func NewLRUCacheWithPolicy[K comparable, V any](size int, policy EvictionPolicy) *LRUCache[K, V] {
+	return NewLRUCache[K, V](size, WithLRUPolicy[K, V](policy))
+}
+
+// NewLRUCacheWithCapacity creates an lru cache bounded by a total charge
+// budget instead of a fixed node count. Each Set computes a per-entry charge
+// via coster and eviction walks the LRU tail of each shard until total
+// charge fits.
+func NewLRUCacheWithCapacity[K comparable, V any](bytes int64, coster func(key K, value V) uint32) *LRUCache[K, V] {
+	c := NewLRUCache[K, V](int(bytes))
+	c.coster = coster
+
+	shardCapacity := uint32(bytes / int64(c.mask+1))
+	for i := uint32(0); i <= c.mask; i++ {
+		c.shards[i].SetCapacity(shardCapacity)
+	}
+
+	return c
+}
+
+// Get returns value for key.
+func (c *LRUCache[K, V]) Get(key K) (value V, ok bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	return c.shards[hash&c.mask].Get(hash, key)
+}
+
+// GetHandle returns a reference-counted handle onto the value for key,
+// pinning it so a concurrent Set cannot recycle the underlying slot. Callers
+// must call Release on the returned handle once done with it.
+func (c *LRUCache[K, V]) GetHandle(key K) (*Handle[V], bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	shard := &c.shards[hash&c.mask]
+	index, value, ok := shard.GetHandle(hash, key)
+	if !ok {
+		return nil, false
+	}
+	return newHandle(value, func() { shard.releaseHandle(index) }), true
+}
+
+// GetOrLoad returns value for key, call loader function by singleflight if value was not in cache.
+func (c *LRUCache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(context.Context, K) (V, error)) (value V, err error, ok bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	value, ok = c.shards[hash&c.mask].Get(hash, key)
+	if !ok {
+		if loader == nil {
+			loader = c.loader
+		}
+		if loader == nil {
+			err = ErrLoaderIsNil
+			return
+		}
+		value, err, ok = c.group.Do(key, func() (V, error) {
+			v, err := loader(ctx, key)
+			if err != nil {
+				return v, err
+			}
+			var charge uint32
+			if c.coster != nil {
+				charge = c.coster(key, v)
+			}
+			c.shards[hash&c.mask].Set(hash, key, v, charge)
+			return v, nil
+		})
+	}
+	return
+}
+
+// Peek returns value, but does not modify its recency.
+func (c *LRUCache[K, V]) Peek(key K) (value V, ok bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	return c.shards[hash&c.mask].Peek(hash, key)
+}
+
+// Set inserts key value pair and returns previous value.
+func (c *LRUCache[K, V]) Set(key K, value V) (prev V, replaced bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	var charge uint32
+	if c.coster != nil {
+		charge = c.coster(key, value)
+	}
+	var evictedKey K
+	var evicted bool
+	prev, replaced, evictedKey, evicted = c.shards[hash&c.mask].Set(hash, key, value, charge)
+	if evicted && c.onEvict != nil {
+		c.onEvict(evictedKey, prev)
+	}
+	return
+}
+
+// SetIfAbsent inserts key value pair, if key is absent in the cache.
+func (c *LRUCache[K, V]) SetIfAbsent(key K, value V) (prev V, replaced bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	var charge uint32
+	if c.coster != nil {
+		charge = c.coster(key, value)
+	}
+	var evictedKey K
+	var evicted bool
+	prev, replaced, evictedKey, evicted = c.shards[hash&c.mask].SetIfAbsent(hash, key, value, charge)
+	if evicted && c.onEvict != nil {
+		c.onEvict(evictedKey, prev)
+	}
+	return
+}
+
+// setOnEvict installs a callback invoked whenever a ring-slot replacement
+// drops a different live key (see onEvict). Package-private: used by
+// IndexedCache to keep its secondary indexes in sync with primary's own
+// eviction, which would otherwise leak entries it never invalidates.
+func (c *LRUCache[K, V]) setOnEvict(fn func(key K, value V)) {
+	c.onEvict = fn
+}
+
+// Delete method deletes value associated with key and returns deleted value (or empty value if key was not in cache).
+func (c *LRUCache[K, V]) Delete(key K) (prev V) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	return c.shards[hash&c.mask].Delete(hash, key)
+}
+
+// Len returns number of cached nodes.
+func (c *LRUCache[K, V]) Len() int {
+	var n uint32
+	for i := uint32(0); i <= c.mask; i++ {
+		n += c.shards[i].Len()
+	}
+	return int(n)
+}
+
+// AppendKeys appends all keys to keys and return the keys.
+func (c *LRUCache[K, V]) AppendKeys(keys []K) []K {
+	for i := uint32(0); i <= c.mask; i++ {
+		keys = c.shards[i].AppendKeys(keys)
+	}
+	return keys
+}
+
+// Stats returns cache stats.
+func (c *LRUCache[K, V]) Stats() (stats Stats) {
+	for i := uint32(0); i <= c.mask; i++ {
+		s := &c.shards[i]
+		s.mu.Lock()
+		stats.EntriesCount += uint64(s.table_length)
+		stats.GetCalls += s.stats_getcalls
+		stats.SetCalls += s.stats_setcalls
+		stats.Misses += s.stats_misses
+		stats.Bytes += uint64(s.used)
+		stats.Capacity += uint64(s.capacity)
+		s.mu.Unlock()
+	}
+	return
+}
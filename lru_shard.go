@@ -0,0 +1,423 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// lrunode is a list of lru node, storing key-value pairs and related information
+type lrunode[K comparable, V any] struct {
+	key     K
+	next    uint32
+	prev    uint32
+	charge  uint32
+	refs    int32
+	pending bool
+	visited bool
+	value   V
+}
+
+type lrubucket struct {
+	hdib  uint32 // bitfield { hash:24 dib:8 }
+	index uint32 // node index
+}
+
+// lrushard is a LRU partition contains a list and a hash table.
+type lrushard[K comparable, V any] struct {
+	mu sync.Mutex
+
+	// the hash table, with 20% extra space than the list for fewer conflicts.
+	table_buckets []uint64 // []lrubucket
+	table_mask    uint32
+	table_length  uint32
+	table_hasher  func(key unsafe.Pointer, seed uintptr) uintptr
+	table_seed    uintptr
+
+	// the list of nodes
+	list []lrunode[K, V]
+
+	// eviction policy, defaults to PolicyLRU; PolicySIEVE walks hand instead
+	// of evicting the strict list tail and skips the move-to-front on Get.
+	policy EvictionPolicy
+	hand   uint32
+
+	// charge-based capacity accounting, 0 means node-count based (unlimited bytes)
+	capacity uint32
+	used     uint32
+
+	// stats
+	stats_getcalls uint64
+	stats_setcalls uint64
+	stats_misses   uint64
+
+	// padding
+	_ [8]byte
+}
+
+func (s *lrushard[K, V]) Init(size uint32, hasher func(key unsafe.Pointer, seed uintptr) uintptr, seed uintptr) {
+	s.list_Init(size)
+	s.table_Init(size, hasher, seed)
+}
+
+// SetCapacity sets the shard's byte/cost budget. A capacity of 0 disables
+// charge-based eviction and falls back to the fixed node-count ring.
+func (s *lrushard[K, V]) SetCapacity(capacity uint32) {
+	s.mu.Lock()
+	s.capacity = capacity
+	s.mu.Unlock()
+}
+
+// SetPolicy selects the shard's eviction policy. Callers should do this
+// right after Init, before the shard sees any traffic.
+func (s *lrushard[K, V]) SetPolicy(policy EvictionPolicy) {
+	s.mu.Lock()
+	s.policy = policy
+	s.hand = s.list[0].prev
+	s.mu.Unlock()
+}
+
+// sieveCandidate walks the SIEVE hand backward from its current position,
+// clearing visited bits, until it lands on an unvisited node to evict.
+// Callers must hold s.mu.
+func (s *lrushard[K, V]) sieveCandidate() uint32 {
+	if s.hand == 0 {
+		s.hand = s.list[0].prev
+	}
+	for {
+		if s.hand == 0 {
+			s.hand = s.list[0].prev
+			continue
+		}
+		node := &s.list[s.hand]
+		if node.visited {
+			node.visited = false
+			s.hand = node.prev
+			continue
+		}
+		break
+	}
+	return s.hand
+}
+
+// nextEvictable returns the index of the next node the active policy wants
+// to reclaim, skipping any node currently checked out via GetHandle. Pinned
+// nodes stay live and are pushed to the front, out of the way of the next
+// eviction attempt. The scan is
+// bounded by table_length: if every live node is pinned, ok is false rather
+// than spinning forever under s.mu. Callers must hold s.mu.
+func (s *lrushard[K, V]) nextEvictable() (index uint32, ok bool) {
+	candidate := func() uint32 {
+		if s.policy == PolicySIEVE {
+			return s.sieveCandidate()
+		}
+		return s.list[0].prev
+	}
+
+	index = candidate()
+	for tries := uint32(0); s.list[index].refs > 0; tries++ {
+		if tries >= s.table_length {
+			return 0, false
+		}
+		s.list_MoveToFront(index)
+		if s.policy == PolicySIEVE {
+			s.hand = s.list[0].prev
+		}
+		index = candidate()
+	}
+	if s.policy == PolicySIEVE {
+		s.hand = s.list[index].prev
+	}
+	return index, true
+}
+
+// lruEvicted records a key/value pair dropped by eviction so callers can
+// notify an eviction callback after releasing s.mu.
+type lruEvicted[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// evictForCharge evicts entries from the list tail, removing them from the
+// table, until there is enough room for charge more, the shard is empty, or
+// every remaining entry is pinned (in which case the shard is left to
+// temporarily exceed capacity rather than block). Callers must hold s.mu.
+func (s *lrushard[K, V]) evictForCharge(charge uint32) (evicted []lruEvicted[K, V]) {
+	if s.capacity == 0 {
+		return nil
+	}
+	for s.table_length > 0 && s.used+charge > s.capacity {
+		// free slots sit at the back of the list: walk from the tail toward
+		// the front to the least recently used live, unpinned entry.
+		index := s.list[0].prev
+		for ; index != 0; index = s.list[index].prev {
+			node := &s.list[index]
+			if i, ok := s.table_Get(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key); ok && i == index && node.refs == 0 {
+				break
+			}
+		}
+		if index == 0 {
+			return evicted
+		}
+		node := &s.list[index]
+		s.used -= node.charge
+		s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+		evicted = append(evicted, lruEvicted[K, V]{key: node.key, value: node.value})
+		var zero V
+		node.value = zero
+		node.charge = 0
+		s.list_MoveToBack(index)
+	}
+	return evicted
+}
+
+// touch records a successful Get against index per the active policy: under
+// PolicyLRU it moves the node to the front of the list; under PolicySIEVE it
+// only flips the visited bit, leaving list order (and hence the hot-path
+// write) untouched.
+func (s *lrushard[K, V]) touch(index uint32) {
+	if s.policy == PolicySIEVE {
+		s.list[index].visited = true
+		return
+	}
+	s.list_MoveToFront(index)
+}
+
+func (s *lrushard[K, V]) Get(hash uint32, key K) (value V, ok bool) {
+	s.mu.Lock()
+
+	s.stats_getcalls++
+
+	if index, exists := s.table_Get(hash, key); exists {
+		s.touch(index)
+		value = s.list[index].value
+		ok = true
+	} else {
+		s.stats_misses++
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+func (s *lrushard[K, V]) Peek(hash uint32, key K) (value V, ok bool) {
+	s.mu.Lock()
+
+	if index, exists := s.table_Get(hash, key); exists {
+		value = s.list[index].value
+		ok = true
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+// GetHandle is like Get but pins the entry in place: it will not be reused
+// by eviction until the returned index is passed to releaseHandle, even if
+// it is overwritten in the meantime.
+func (s *lrushard[K, V]) GetHandle(hash uint32, key K) (index uint32, value V, ok bool) {
+	s.mu.Lock()
+
+	if idx, exists := s.table_Get(hash, key); exists {
+		node := &s.list[idx]
+		node.refs++
+		s.list_MoveToFront(idx)
+		index, value, ok = idx, node.value, true
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+// releaseHandle drops a reference taken by GetHandle. If the node was
+// deleted while checked out, releasing the last reference recycles its
+// slot: the table entry for its key is already gone (Delete removes it up
+// front), so it only remains to drop the value and
+// send the node to the back of the list for reuse.
+func (s *lrushard[K, V]) releaseHandle(index uint32) {
+	s.mu.Lock()
+
+	node := &s.list[index]
+	node.refs--
+	if node.refs == 0 && node.pending {
+		node.pending = false
+		var zero V
+		node.value = zero
+		node.charge = 0
+		s.list_MoveToBack(index)
+	}
+
+	s.mu.Unlock()
+}
+
+// Set inserts key value pair and returns previous value. charge is optional
+// (defaulting to 0, i.e. node-count accounting only) so existing callers
+// that never opted into charge-based capacity keep a 3-argument call site.
+// evicted reports whether the reused slot last held a different key
+// (evictedKey), so LRUCache can drive an eviction callback; for a slot that
+// was never used, or was already freed, that key is not live any more.
+func (s *lrushard[K, V]) Set(hash uint32, key K, value V, charge ...uint32) (prev V, replaced bool, evictedKey K, evicted bool) {
+	var c uint32
+	if len(charge) > 0 {
+		c = charge[0]
+	}
+
+	s.mu.Lock()
+
+	s.stats_setcalls++
+
+	if index, exists := s.table_Get(hash, key); exists {
+		node := &s.list[index]
+		previousValue := node.value
+		s.list_MoveToFront(index)
+		node.value = value
+		if s.capacity > 0 {
+			s.used = s.used - node.charge + c
+		}
+		node.charge = c
+		prev = previousValue
+		replaced = true
+
+		s.mu.Unlock()
+		return
+	}
+
+	// evictForCharge only fires when the shard was configured with a byte
+	// budget (SetCapacity); the ring-slot replacement below is what recycles
+	// entries for the common node-count-bounded cache, and is what reports
+	// evictedKey/evicted back to the caller.
+	s.evictForCharge(c)
+
+	index, ok := s.nextEvictable()
+	if !ok {
+		// Every node in the shard is pinned via GetHandle: there is no slot
+		// to reclaim right now. Drop the Set rather than spin or corrupt a
+		// pinned handle's value.
+		s.mu.Unlock()
+		return
+	}
+	node := &s.list[index]
+	evictedValue := node.value
+	if key != node.key {
+		s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+		evictedKey, evicted = node.key, true
+	}
+	if s.capacity > 0 {
+		s.used = s.used - node.charge + c
+	}
+
+	node.key = key
+	node.value = value
+	node.charge = c
+	s.table_Set(hash, key, index)
+	s.list_MoveToFront(index)
+	prev = evictedValue
+
+	s.mu.Unlock()
+	return
+}
+
+// SetIfAbsent inserts key value pair, if key is absent in the cache. charge
+// is optional, see Set.
+func (s *lrushard[K, V]) SetIfAbsent(hash uint32, key K, value V, charge ...uint32) (prev V, replaced bool, evictedKey K, evicted bool) {
+	var c uint32
+	if len(charge) > 0 {
+		c = charge[0]
+	}
+
+	s.mu.Lock()
+
+	if index, exists := s.table_Get(hash, key); exists {
+		prev = s.list[index].value
+		s.mu.Unlock()
+		return
+	}
+
+	s.stats_setcalls++
+
+	s.evictForCharge(c)
+
+	index, ok := s.nextEvictable()
+	if !ok {
+		// Every node in the shard is pinned via GetHandle: there is no slot
+		// to reclaim right now. Drop the Set rather than spin or corrupt a
+		// pinned handle's value.
+		s.mu.Unlock()
+		return
+	}
+	node := &s.list[index]
+	evictedValue := node.value
+	previousKey := node.key
+	s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+	if s.capacity > 0 {
+		s.used = s.used - node.charge + c
+	}
+
+	node.key = key
+	node.value = value
+	node.charge = c
+	s.table_Set(hash, key, index)
+	s.list_MoveToFront(index)
+	prev = evictedValue
+	evictedKey, evicted = previousKey, true
+
+	s.mu.Unlock()
+	return
+}
+
+func (s *lrushard[K, V]) Delete(hash uint32, key K) (v V) {
+	s.mu.Lock()
+
+	if index, exists := s.table_Get(hash, key); exists {
+		node := &s.list[index]
+		value := node.value
+
+		s.table_Delete(hash, key)
+		if s.capacity > 0 {
+			s.used -= node.charge
+		}
+
+		if node.refs > 0 {
+			// Pinned via GetHandle: defer clobbering the value and recycling
+			// the slot to releaseHandle.
+			node.pending = true
+		} else {
+			s.list_MoveToBack(index)
+			var zero V
+			node.value = zero
+			node.charge = 0
+		}
+		v = value
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+func (s *lrushard[K, V]) Len() (n uint32) {
+	s.mu.Lock()
+	// inlining s.table_Len()
+	n = s.table_length
+	s.mu.Unlock()
+
+	return
+}
+
+func (s *lrushard[K, V]) AppendKeys(dst []K) []K {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*lrubucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		dst = append(dst, s.list[b.index].key)
+	}
+	s.mu.Unlock()
+
+	return dst
+}
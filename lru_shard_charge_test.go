@@ -0,0 +1,47 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "testing"
+
+func TestLRUShardChargeEviction(t *testing.T) {
+	var s lrushard[string, int]
+	s.Init(4, getRuntimeHasher[string](), 0)
+	s.SetCapacity(3)
+
+	s.Set(lruShardHash(&s, "a"), "a", 1, 1)
+	s.Set(lruShardHash(&s, "b"), "b", 2, 2)
+	if got := s.used; got != 3 {
+		t.Fatalf("used = %d, want 3", got)
+	}
+
+	// "c" needs 1 more than the remaining budget: the LRU tail ("a") must be
+	// evicted to make room before "c" is inserted.
+	s.Set(lruShardHash(&s, "c"), "c", 3, 1)
+	if got := s.used; got != 3 {
+		t.Fatalf("used after charge eviction = %d, want 3", got)
+	}
+	if _, ok := s.table_Get(lruShardHash(&s, "a"), "a"); ok {
+		t.Fatalf("\"a\" should have been evicted to make room for \"c\"")
+	}
+	if _, ok := s.table_Get(lruShardHash(&s, "b"), "b"); !ok {
+		t.Fatalf("\"b\" should still be present")
+	}
+}
+
+func TestLRUShardDeleteReleasesCharge(t *testing.T) {
+	var s lrushard[string, int]
+	s.Init(4, getRuntimeHasher[string](), 0)
+	s.SetCapacity(10)
+
+	hash := lruShardHash(&s, "a")
+	s.Set(hash, "a", 1, 4)
+	if got := s.used; got != 4 {
+		t.Fatalf("used = %d, want 4", got)
+	}
+
+	s.Delete(hash, "a")
+	if got := s.used; got != 0 {
+		t.Fatalf("used after Delete = %d, want 0", got)
+	}
+}
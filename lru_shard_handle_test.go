@@ -0,0 +1,53 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func lruShardHash(s *lrushard[string, int], key string) uint32 {
+	return uint32(s.table_hasher(noescape(unsafe.Pointer(&key)), s.table_seed))
+}
+
+func TestLRUShardHandlePinsAgainstEviction(t *testing.T) {
+	var s lrushard[string, int]
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	ha := lruShardHash(&s, "a")
+	s.Set(ha, "a", 1)
+
+	index, value, ok := s.GetHandle(ha, "a")
+	if !ok || value != 1 {
+		t.Fatalf("GetHandle(a) = %v, %v, %v, want 1, true", value, ok, index)
+	}
+
+	// Insert enough other keys to cycle the whole ring; "a" must never be
+	// reused as a victim while its handle is outstanding.
+	s.Set(lruShardHash(&s, "b"), "b", 2)
+	s.Set(lruShardHash(&s, "c"), "c", 3)
+
+	if _, ok := s.table_Get(ha, "a"); !ok {
+		t.Fatalf("pinned key \"a\" was evicted while its handle was outstanding")
+	}
+
+	s.releaseHandle(index)
+}
+
+func TestLRUShardNextEvictableAllPinned(t *testing.T) {
+	var s lrushard[string, int]
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	for _, key := range []string{"a", "b"} {
+		hash := lruShardHash(&s, key)
+		s.Set(hash, key, 1)
+		if _, _, ok := s.GetHandle(hash, key); !ok {
+			t.Fatalf("GetHandle(%s) failed", key)
+		}
+	}
+
+	if _, ok := s.nextEvictable(); ok {
+		t.Fatalf("nextEvictable() should report no victim when every node is pinned, not pick one")
+	}
+}
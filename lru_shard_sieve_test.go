@@ -0,0 +1,65 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import "testing"
+
+// TestLRUShardSievePreservesVisited fills a SIEVE shard, marks every entry
+// visited via Get except one, and checks that the lone unvisited entry is
+// the one reclaimed: sieveCandidate must clear visited bits on the others
+// and keep walking rather than evicting the first (possibly visited) node
+// it sees.
+func TestLRUShardSievePreservesVisited(t *testing.T) {
+	var s lrushard[string, int]
+	s.Init(3, getRuntimeHasher[string](), 0)
+	s.SetPolicy(PolicySIEVE)
+
+	s.Set(lruShardHash(&s, "a"), "a", 1)
+	s.Set(lruShardHash(&s, "b"), "b", 2)
+	s.Set(lruShardHash(&s, "c"), "c", 3)
+
+	// Touch "a" and "b" so they're visited; "c" is left untouched.
+	s.Get(lruShardHash(&s, "a"), "a")
+	s.Get(lruShardHash(&s, "b"), "b")
+
+	s.Set(lruShardHash(&s, "d"), "d", 4)
+
+	if _, ok := s.table_Get(lruShardHash(&s, "c"), "c"); ok {
+		t.Fatalf("\"c\" is the only unvisited entry and should have been evicted")
+	}
+	if _, ok := s.table_Get(lruShardHash(&s, "a"), "a"); !ok {
+		t.Fatalf("visited entry \"a\" should have survived eviction")
+	}
+	if _, ok := s.table_Get(lruShardHash(&s, "b"), "b"); !ok {
+		t.Fatalf("visited entry \"b\" should have survived eviction")
+	}
+	if _, ok := s.table_Get(lruShardHash(&s, "d"), "d"); !ok {
+		t.Fatalf("newly inserted \"d\" should be present")
+	}
+}
+
+// TestLRUShardSieveGetDoesNotReorderList confirms the documented hot-path
+// optimization: under PolicySIEVE, touch() only flips the visited bit and
+// leaves list order untouched, unlike PolicyLRU's move-to-front.
+func TestLRUShardSieveGetDoesNotReorderList(t *testing.T) {
+	var s lrushard[string, int]
+	s.Init(3, getRuntimeHasher[string](), 0)
+	s.SetPolicy(PolicySIEVE)
+
+	ha := lruShardHash(&s, "a")
+	s.Set(ha, "a", 1)
+	s.Set(lruShardHash(&s, "b"), "b", 2)
+
+	front := s.list[0].next
+	s.Get(ha, "a")
+	if s.list[0].next != front {
+		t.Fatalf("Get under PolicySIEVE moved the list front from %d to %d", front, s.list[0].next)
+	}
+}
+
+func TestNewLRUCacheWithPolicySetsSieve(t *testing.T) {
+	c := NewLRUCacheWithPolicy[string, int](8, PolicySIEVE)
+	if c.shards[0].policy != PolicySIEVE {
+		t.Fatalf("NewLRUCacheWithPolicy did not set PolicySIEVE on shard 0")
+	}
+}
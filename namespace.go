@@ -0,0 +1,155 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// BytesNamespace is a handle onto a single namespace within a BytesCache. It
+// behaves like a private BytesCache sharing the parent's capacity budget,
+// letting callers group entries (per-tenant, per-table) for bulk eviction
+// via the parent's EvictNS.
+type BytesNamespace struct {
+	c  *BytesCache
+	ns uint32
+}
+
+// Namespace returns a handle scoped to namespace id. Namespace ids are
+// caller-defined and folded to 32 bits (low half XOR high half), so ids that
+// fold to the same value share one namespace; otherwise equal keys set
+// through different handles are kept apart by their namespace-mixed hash.
+func (c *BytesCache) Namespace(id uint64) *BytesNamespace {
+	return &BytesNamespace{c: c, ns: uint32(id) ^ uint32(id>>32)}
+}
+
+// EvictNS drops every entry tagged with namespace id across all shards and
+// returns the number of entries evicted.
+func (c *BytesCache) EvictNS(id uint64) int {
+	ns := uint32(id) ^ uint32(id>>32)
+	var n uint32
+	for i := uint32(0); i <= c.mask; i++ {
+		n += c.shards[i].EvictNS(ns)
+	}
+	return int(n)
+}
+
+// Get returns value for key within this namespace.
+func (h *BytesNamespace) Get(key []byte) (value []byte, ok bool) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	return h.c.shards[hash&h.c.mask].Get(hash, key)
+}
+
+// Peek returns value for key within this namespace, without modifying recency.
+func (h *BytesNamespace) Peek(key []byte) (value []byte, ok bool) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	return h.c.shards[hash&h.c.mask].Peek(hash, key)
+}
+
+// Set inserts key value pair within this namespace.
+func (h *BytesNamespace) Set(key []byte, value []byte) (prev []byte, replaced bool) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	var charge uint32
+	if h.c.coster != nil {
+		charge = h.c.coster(key, value)
+	}
+	return h.c.shards[hash&h.c.mask].Set(hash, key, value, charge, h.ns)
+}
+
+// Delete deletes the value associated with key within this namespace.
+func (h *BytesNamespace) Delete(key []byte) (prev []byte) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	return h.c.shards[hash&h.c.mask].Delete(hash, key)
+}
+
+// AppendKeys appends all keys belonging to this namespace to keys and
+// returns the keys.
+func (h *BytesNamespace) AppendKeys(keys [][]byte) [][]byte {
+	for i := uint32(0); i <= h.c.mask; i++ {
+		keys = h.c.shards[i].AppendKeysNS(keys, h.ns)
+	}
+	return keys
+}
+
+// Len returns the number of cached nodes in this namespace.
+func (h *BytesNamespace) Len() int {
+	var n uint32
+	for i := uint32(0); i <= h.c.mask; i++ {
+		n += h.c.shards[i].LenNS(h.ns)
+	}
+	return int(n)
+}
+
+// TTLNamespace is a handle onto a single namespace within a TTLCache. It
+// behaves like a private TTLCache sharing the parent's capacity budget,
+// letting callers group entries (per-tenant, per-table) for bulk eviction
+// via the parent's EvictNS.
+type TTLNamespace[K comparable, V any] struct {
+	c  *TTLCache[K, V]
+	ns uint32
+}
+
+// Namespace returns a handle scoped to namespace id. Namespace ids are
+// caller-defined and folded to 32 bits (low half XOR high half), so ids that
+// fold to the same value share one namespace; otherwise equal keys set
+// through different handles are kept apart by their namespace-mixed hash.
+func (c *TTLCache[K, V]) Namespace(id uint64) *TTLNamespace[K, V] {
+	return &TTLNamespace[K, V]{c: c, ns: uint32(id) ^ uint32(id>>32)}
+}
+
+// EvictNS drops every entry tagged with namespace id across all shards and
+// returns the number of entries evicted.
+func (c *TTLCache[K, V]) EvictNS(id uint64) int {
+	ns := uint32(id) ^ uint32(id>>32)
+	var n uint32
+	for i := uint32(0); i <= c.mask; i++ {
+		n += c.shards[i].EvictNS(ns)
+	}
+	return int(n)
+}
+
+// Get returns value for key within this namespace.
+func (h *TTLNamespace[K, V]) Get(key K) (value V, ok bool) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	return h.c.shards[hash&h.c.mask].Get(hash, key)
+}
+
+// Peek returns value for key within this namespace, without modifying recency.
+func (h *TTLNamespace[K, V]) Peek(key K) (value V, ok bool) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	value, _, ok = h.c.shards[hash&h.c.mask].Peek(hash, key)
+	return
+}
+
+// Set inserts key value pair with ttl within this namespace.
+func (h *TTLNamespace[K, V]) Set(key K, value V, ttl time.Duration) (prev V, replaced bool) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	return h.c.shards[hash&h.c.mask].Set(hash, key, value, ttl, 0, h.ns)
+}
+
+// Delete deletes the value associated with key within this namespace.
+func (h *TTLNamespace[K, V]) Delete(key K) (prev V) {
+	hash := mixNamespace(uint32(h.c.hasher(noescape(unsafe.Pointer(&key)), h.c.seed)), h.ns)
+	return h.c.shards[hash&h.c.mask].Delete(hash, key)
+}
+
+// AppendKeys appends all live keys belonging to this namespace to keys and
+// returns the keys.
+func (h *TTLNamespace[K, V]) AppendKeys(keys []K) []K {
+	now := atomic.LoadUint32(&clock)
+	for i := uint32(0); i <= h.c.mask; i++ {
+		keys = h.c.shards[i].AppendKeysNS(keys, now, h.ns)
+	}
+	return keys
+}
+
+// Len returns the number of cached nodes in this namespace.
+func (h *TTLNamespace[K, V]) Len() int {
+	var n uint32
+	for i := uint32(0); i <= h.c.mask; i++ {
+		n += h.c.shards[i].LenNS(h.ns)
+	}
+	return int(n)
+}
@@ -0,0 +1,62 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBytesCacheNamespaceEvictNS(t *testing.T) {
+	c := NewBytesCache[string, []byte](16)
+
+	tenantA := c.Namespace(1)
+	tenantB := c.Namespace(2)
+
+	tenantA.Set([]byte("k"), []byte("a-value"))
+	tenantB.Set([]byte("k"), []byte("b-value"))
+
+	if v, ok := tenantA.Get([]byte("k")); !ok || string(v) != "a-value" {
+		t.Fatalf("tenantA.Get(k) = %q, %v, want a-value, true", v, ok)
+	}
+	if v, ok := tenantB.Get([]byte("k")); !ok || string(v) != "b-value" {
+		t.Fatalf("tenantB.Get(k) = %q, %v, want b-value, true", v, ok)
+	}
+
+	if n := c.EvictNS(1); n != 1 {
+		t.Fatalf("EvictNS(1) = %d, want 1", n)
+	}
+	if _, ok := tenantA.Get([]byte("k")); ok {
+		t.Fatalf("tenantA's entry should be gone after EvictNS(1)")
+	}
+	if _, ok := tenantB.Get([]byte("k")); !ok {
+		t.Fatalf("tenantB's entry should survive EvictNS(1)")
+	}
+}
+
+func TestTTLCacheNamespaceEvictNS(t *testing.T) {
+	c := NewTTLCache[string, int](16)
+
+	tenantA := c.Namespace(1)
+	tenantB := c.Namespace(2)
+
+	tenantA.Set("k", 1, time.Minute)
+	tenantB.Set("k", 2, time.Minute)
+
+	if v, ok := tenantA.Get("k"); !ok || v != 1 {
+		t.Fatalf("tenantA.Get(k) = %v, %v, want 1, true", v, ok)
+	}
+	if v, ok := tenantB.Get("k"); !ok || v != 2 {
+		t.Fatalf("tenantB.Get(k) = %v, %v, want 2, true", v, ok)
+	}
+
+	if n := c.EvictNS(1); n != 1 {
+		t.Fatalf("EvictNS(1) = %d, want 1", n)
+	}
+	if _, ok := tenantA.Get("k"); ok {
+		t.Fatalf("tenantA's entry should be gone after EvictNS(1)")
+	}
+	if _, ok := tenantB.Get("k"); !ok {
+		t.Fatalf("tenantB's entry should survive EvictNS(1)")
+	}
+}
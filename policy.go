@@ -0,0 +1,21 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+// EvictionPolicy selects the algorithm a shard uses to pick a victim when it
+// is full. The zero value, PolicyLRU, is the original strict recency order.
+type EvictionPolicy uint8
+
+const (
+	// PolicyLRU evicts the least recently used entry. Every Get moves the
+	// entry to the front of the list.
+	PolicyLRU EvictionPolicy = iota
+
+	// PolicySIEVE implements the SIEVE algorithm: Get only flips a 1-bit
+	// visited flag instead of moving the entry, avoiding the list-write on
+	// the hot read path. Eviction walks a "hand" pointer backward from the
+	// tail, clearing visited bits until it finds an unvisited entry to
+	// evict. It is scan-resistant and tends to match or beat LRU hit ratio
+	// on skewed workloads.
+	PolicySIEVE
+)
@@ -0,0 +1,226 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// TTLCache implements TTL Cache with least recent used eviction policy.
+type TTLCache[K comparable, V any] struct {
+	shards [512]ttlshard[K, V]
+	mask   uint32
+	hasher func(key unsafe.Pointer, seed uintptr) uintptr
+	seed   uintptr
+	loader func(ctx context.Context, key K) (value V, ttl time.Duration, err error)
+	group  singleflight_Group[K, V]
+
+	janitorStop chan struct{}
+	janitorDone chan struct{}
+}
+
+// ttlCacheOptions holds construction-time options applied by TTLCacheOption.
+type ttlCacheOptions struct {
+	janitor time.Duration
+	policy  EvictionPolicy
+}
+
+// TTLCacheOption configures a TTLCache at construction time, see NewTTLCache.
+type TTLCacheOption func(*ttlCacheOptions)
+
+// WithJanitor starts a background goroutine that sweeps every shard for
+// expired entries every interval, so Stop() finalizers run for cold keys
+// that are never Get-ed again instead of leaking until eviction. Without
+// this option, expiration stays lazy (checked on the next Get of that key).
+func WithJanitor(interval time.Duration) TTLCacheOption {
+	return func(o *ttlCacheOptions) {
+		o.janitor = interval
+	}
+}
+
+// WithPolicy selects the eviction policy used by every shard. Defaults to
+// PolicyLRU.
+func WithPolicy(policy EvictionPolicy) TTLCacheOption {
+	return func(o *ttlCacheOptions) {
+		o.policy = policy
+	}
+}
+
+// NewTTLCache creates ttl cache with size capacity.
+func NewTTLCache[K comparable, V any](size int, options ...TTLCacheOption) *TTLCache[K, V] {
+	var o ttlCacheOptions
+	for _, option := range options {
+		option(&o)
+	}
+
+	c := new(TTLCache[K, V])
+
+	if c.hasher == nil {
+		c.hasher = getRuntimeHasher[K]()
+	}
+	if c.seed == 0 {
+		c.seed = uintptr(fastrand64())
+	}
+
+	if isamd64 {
+		// pre-alloc lists and tables for compactness
+		shardsize := (uint32(size) + c.mask) / (c.mask + 1)
+		shardlists := make([]ttlnode[K, V], (shardsize+1)*(c.mask+1))
+		tablesize := ttlNewTableSize(uint32(shardsize))
+		tablebuckets := make([]uint64, tablesize*(c.mask+1))
+		for i := uint32(0); i <= c.mask; i++ {
+			c.shards[i].list = shardlists[i*(shardsize+1) : (i+1)*(shardsize+1)]
+			c.shards[i].table_buckets = tablebuckets[i*tablesize : (i+1)*tablesize]
+			c.shards[i].Init(shardsize, c.hasher, c.seed)
+		}
+	} else {
+		shardsize := (uint32(size) + c.mask) / (c.mask + 1)
+		for i := uint32(0); i <= c.mask; i++ {
+			c.shards[i].Init(shardsize, c.hasher, c.seed)
+		}
+	}
+
+	if o.policy != PolicyLRU {
+		for i := uint32(0); i <= c.mask; i++ {
+			c.shards[i].SetPolicy(o.policy)
+		}
+	}
+
+	if o.janitor > 0 {
+		c.janitorStop = make(chan struct{})
+		c.janitorDone = make(chan struct{})
+		go c.runJanitor(o.janitor)
+	}
+
+	return c
+}
+
+func (c *TTLCache[K, V]) runJanitor(interval time.Duration) {
+	defer close(c.janitorDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := atomic.LoadUint32(&clock)
+			for i := uint32(0); i <= c.mask; i++ {
+				c.shards[i].sweepExpired(now)
+			}
+		case <-c.janitorStop:
+			return
+		}
+	}
+}
+
+// Close stops the background janitor, if any, and drains every shard,
+// invoking each live entry's Stop() finalizer so resources pooled in the
+// cache (files, DB connections, ...) are released deterministically.
+func (c *TTLCache[K, V]) Close() error {
+	if c.janitorStop != nil {
+		close(c.janitorStop)
+		<-c.janitorDone
+	}
+
+	for i := uint32(0); i <= c.mask; i++ {
+		c.shards[i].drain()
+	}
+
+	return nil
+}
+
+// Get returns value for key.
+func (c *TTLCache[K, V]) Get(key K) (value V, ok bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	return c.shards[hash&c.mask].Get(hash, key)
+}
+
+// GetOrLoad returns value for key, call loader function by singleflight if value was not in cache.
+func (c *TTLCache[K, V]) GetOrLoad(ctx context.Context, key K, loader func(context.Context, K) (V, time.Duration, error)) (value V, err error, ok bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	value, ok = c.shards[hash&c.mask].Get(hash, key)
+	if !ok {
+		if loader == nil {
+			loader = c.loader
+		}
+		if loader == nil {
+			err = ErrLoaderIsNil
+			return
+		}
+		value, err, ok = c.group.Do(key, func() (V, error) {
+			v, ttl, err := loader(ctx, key)
+			if err != nil {
+				return v, err
+			}
+			c.shards[hash&c.mask].Set(hash, key, v, ttl, 0, 0)
+			return v, nil
+		})
+	}
+	return
+}
+
+// GetHandle returns a reference-counted handle onto the value for key,
+// pinning it so it survives eviction or expiry until the handle is released.
+// Callers must call Release on the returned handle once done with it.
+func (c *TTLCache[K, V]) GetHandle(key K) (*Handle[V], bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	shard := &c.shards[hash&c.mask]
+	index, value, ok := shard.GetHandle(hash, key)
+	if !ok {
+		return nil, false
+	}
+	return newHandle(value, func() { shard.releaseHandle(index) }), true
+}
+
+// Peek returns value, but does not modify its recency.
+func (c *TTLCache[K, V]) Peek(key K) (value V, ok bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	value, _, ok = c.shards[hash&c.mask].Peek(hash, key)
+	return
+}
+
+// Set inserts key value pair with ttl and returns previous value.
+func (c *TTLCache[K, V]) Set(key K, value V, ttl time.Duration) (prev V, replaced bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	return c.shards[hash&c.mask].Set(hash, key, value, ttl, 0, 0)
+}
+
+// SetIfAbsent inserts key value pair with ttl, if key is absent in the cache.
+func (c *TTLCache[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) (prev V, replaced bool) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	return c.shards[hash&c.mask].SetIfAbsent(hash, key, value, ttl, 0)
+}
+
+// Delete method deletes value associated with key and returns deleted value (or empty value if key was not in cache).
+func (c *TTLCache[K, V]) Delete(key K) (prev V) {
+	hash := uint32(c.hasher(noescape(unsafe.Pointer(&key)), c.seed))
+	return c.shards[hash&c.mask].Delete(hash, key)
+}
+
+// Len returns number of cached nodes.
+func (c *TTLCache[K, V]) Len() int {
+	var n uint32
+	for i := uint32(0); i <= c.mask; i++ {
+		n += c.shards[i].Len()
+	}
+	return int(n)
+}
+
+// Stats returns cache stats.
+func (c *TTLCache[K, V]) Stats() (stats Stats) {
+	for i := uint32(0); i <= c.mask; i++ {
+		s := &c.shards[i]
+		s.mu.Lock()
+		stats.EntriesCount += uint64(s.table_length)
+		stats.GetCalls += s.stats_getcalls
+		stats.SetCalls += s.stats_setcalls
+		stats.Misses += s.stats_misses
+		stats.Expired += s.stats_expired
+		s.mu.Unlock()
+	}
+	return
+}
@@ -16,9 +16,25 @@ type ttlnode[K comparable, V any] struct {
 	next    uint32
 	prev    uint32
 	ttl     uint32
+	charge  uint32
+	ns      uint32
+	refs    int32
+	pending bool
+	visited bool
 	value   V
 }
 
+// mixNamespace folds a namespace id into a key hash so that entries from
+// different namespaces land in different buckets/shards even when their
+// keys are otherwise equal.
+func mixNamespace(hash uint32, ns uint32) uint32 {
+	h := hash ^ ns
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	return h
+}
+
 type ttlbucket struct {
 	hdib  uint32 // bitfield { hash:24 dib:8 }
 	index uint32 // node index
@@ -40,13 +56,23 @@ type ttlshard[K comparable, V any] struct {
 
 	sliding bool
 
+	// eviction policy, defaults to PolicyLRU; PolicySIEVE walks hand instead
+	// of evicting the strict list tail and skips the move-to-front on Get.
+	policy EvictionPolicy
+	hand   uint32
+
+	// charge-based capacity accounting, 0 means node-count based (unlimited bytes)
+	capacity uint32
+	used     uint32
+
 	// stats
 	stats_getcalls uint64
 	stats_setcalls uint64
 	stats_misses   uint64
+	stats_expired  uint64
 
 	// padding
-	_ [16]byte
+	_ [8]byte
 }
 
 func (s *ttlshard[K, V]) Init(size uint32, hasher func(key unsafe.Pointer, seed uintptr) uintptr, seed uintptr) {
@@ -54,11 +80,131 @@ func (s *ttlshard[K, V]) Init(size uint32, hasher func(key unsafe.Pointer, seed
 	s.table_Init(size, hasher, seed)
 }
 
+// SetCapacity sets the shard's byte/cost budget. A capacity of 0 disables
+// charge-based eviction and falls back to the fixed node-count ring.
+func (s *ttlshard[K, V]) SetCapacity(capacity uint32) {
+	s.mu.Lock()
+	s.capacity = capacity
+	s.mu.Unlock()
+}
+
+// SetPolicy selects the shard's eviction policy. Callers should do this
+// right after Init, before the shard sees any traffic.
+func (s *ttlshard[K, V]) SetPolicy(policy EvictionPolicy) {
+	s.mu.Lock()
+	s.policy = policy
+	s.hand = s.list[0].prev
+	s.mu.Unlock()
+}
+
+// sieveCandidate walks the SIEVE hand backward from its current position,
+// clearing visited bits, until it lands on an unvisited node to evict.
+// Callers must hold s.mu.
+func (s *ttlshard[K, V]) sieveCandidate() uint32 {
+	if s.hand == 0 {
+		s.hand = s.list[0].prev
+	}
+	for {
+		if s.hand == 0 {
+			s.hand = s.list[0].prev
+			continue
+		}
+		node := &s.list[s.hand]
+		if node.visited {
+			node.visited = false
+			s.hand = node.prev
+			continue
+		}
+		break
+	}
+	return s.hand
+}
+
+// nextEvictable returns the index of the next node the active policy wants
+// to reclaim, skipping any node currently checked out via GetHandle. Pinned
+// nodes stay live and are pushed to the front, out of the way of the next
+// eviction attempt. Callers must hold s.mu.
+//
+// The scan is bounded by table_length: if every live node is pinned, ok is
+// false rather than spinning forever under s.mu (which would deadlock,
+// since releaseHandle needs the same lock to drop a ref).
+func (s *ttlshard[K, V]) nextEvictable() (index uint32, ok bool) {
+	candidate := func() uint32 {
+		if s.policy == PolicySIEVE {
+			return s.sieveCandidate()
+		}
+		return s.list[0].prev
+	}
+
+	index = candidate()
+	for tries := uint32(0); s.list[index].refs > 0; tries++ {
+		if tries >= s.table_length {
+			return 0, false
+		}
+		s.list_MoveToFront(index)
+		if s.policy == PolicySIEVE {
+			s.hand = s.list[0].prev
+		}
+		index = candidate()
+	}
+	if s.policy == PolicySIEVE {
+		s.hand = s.list[index].prev
+	}
+	return index, true
+}
+
+// evictForCharge evicts entries from the list tail, stopping their values and
+// removing them from the table, until there is enough room for charge more,
+// the shard is empty, or every remaining entry is pinned (in which case the
+// shard is left to temporarily exceed capacity rather than block). Callers
+// must hold s.mu.
+func (s *ttlshard[K, V]) evictForCharge(charge uint32) {
+	if s.capacity == 0 {
+		return
+	}
+	for s.table_length > 0 && s.used+charge > s.capacity {
+		// free slots sit at the back of the list: walk from the tail toward
+		// the front to the least recently used live, unpinned entry.
+		index := s.list[0].prev
+		for ; index != 0; index = s.list[index].prev {
+			node := &s.list[index]
+			if i, ok := s.table_Get(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key); ok && i == index && node.refs == 0 {
+				break
+			}
+		}
+		if index == 0 {
+			return
+		}
+		node := (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0])))
+		if st, ok := any(node.value).(stoper); ok {
+			_ = st.Stop()
+		}
+		s.used -= node.charge
+		s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+		var zero V
+		node.value = zero
+		node.charge = 0
+		s.list_MoveToBack(index)
+	}
+}
+
 // stoper is an interface that defines a method to stop an operation.
 type stoper interface {
 	Stop() error
 }
 
+// touch records a successful Get against index per the active policy: under
+// PolicyLRU it moves the node to the front of the list; under PolicySIEVE it
+// only flips the visited bit, leaving list order (and hence the hot-path
+// write) untouched.
+func (s *ttlshard[K, V]) touch(index uint32) {
+	if s.policy == PolicySIEVE {
+		s.list[index].visited = true
+		return
+	}
+	s.list_MoveToFront(index)
+}
+
 func (s *ttlshard[K, V]) Get(hash uint32, key K) (value V, ok bool) {
 	s.mu.Lock()
 
@@ -66,7 +212,7 @@ func (s *ttlshard[K, V]) Get(hash uint32, key K) (value V, ok bool) {
 
 	if index, exists := s.table_Get(hash, key); exists {
 		if expires := s.list[index].expires; expires == 0 {
-			s.list_MoveToFront(index)
+			s.touch(index)
 			// value = s.list[index].value
 			value = (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0]))).value
 			ok = true
@@ -74,22 +220,33 @@ func (s *ttlshard[K, V]) Get(hash uint32, key K) (value V, ok bool) {
 			if s.sliding {
 				s.list[index].expires = now + s.list[index].ttl
 			}
-			s.list_MoveToFront(index)
+			s.touch(index)
 			// value = s.list[index].value
 			value = (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0]))).value
 			ok = true
 		} else {
 
-			val := (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0]))).value
-			if st, ok := any(val).(stoper); ok {
-				_ = st.Stop()
-			}
+			node := (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0])))
 
-			s.list_MoveToBack(index)
-			// s.list[index].value = value
-			(*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0]))).value = value
+			// The key is always gone from the table as of now, so a later
+			// Get correctly reports a miss. But if the node is pinned via
+			// GetHandle, defer clobbering its value/Stop() to releaseHandle
+			// so a handle holder never sees its value change or finalize
+			// out from under it.
 			s.table_Delete(hash, key)
+			if node.refs > 0 {
+				node.pending = true
+			} else {
+				if st, ok := any(node.value).(stoper); ok {
+					_ = st.Stop()
+				}
+				s.list_MoveToBack(index)
+				var zero V
+				node.value = zero
+				node.charge = 0
+			}
 			s.stats_misses++
+			s.stats_expired++
 		}
 	} else {
 		s.stats_misses++
@@ -116,7 +273,52 @@ func (s *ttlshard[K, V]) Peek(hash uint32, key K) (value V, expires int64, ok bo
 	return
 }
 
-func (s *ttlshard[K, V]) SetIfAbsent(hash uint32, key K, value V, ttl time.Duration) (prev V, replaced bool) {
+// GetHandle is like Get but pins the entry in place: it will not be reused
+// by eviction until the returned index is passed to releaseHandle, even if
+// it expires or is overwritten in the meantime.
+func (s *ttlshard[K, V]) GetHandle(hash uint32, key K) (index uint32, value V, ok bool) {
+	s.mu.Lock()
+
+	if idx, exists := s.table_Get(hash, key); exists {
+		node := (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(idx)*unsafe.Sizeof(s.list[0])))
+		if expires := node.expires; expires == 0 || atomic.LoadUint32(&clock) < expires {
+			node.refs++
+			s.list_MoveToFront(idx)
+			index, value, ok = idx, node.value, true
+		}
+	}
+
+	s.mu.Unlock()
+
+	return
+}
+
+// releaseHandle drops a reference taken by GetHandle. If the node was
+// reclaimed by eviction or expiry while checked out, releasing the last
+// reference finalizes it via its Stop() method and returns its slot to the
+// free ring: the table entry for its key is already gone (the evictor/sweeper
+// deletes it up front), so it only remains to drop the value and send the
+// node to the back of the list for reuse.
+func (s *ttlshard[K, V]) releaseHandle(index uint32) {
+	s.mu.Lock()
+
+	node := (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0])))
+	node.refs--
+	if node.refs == 0 && node.pending {
+		if st, ok := any(node.value).(stoper); ok {
+			_ = st.Stop()
+		}
+		node.pending = false
+		var zero V
+		node.value = zero
+		node.charge = 0
+		s.list_MoveToBack(index)
+	}
+
+	s.mu.Unlock()
+}
+
+func (s *ttlshard[K, V]) SetIfAbsent(hash uint32, key K, value V, ttl time.Duration, charge uint32) (prev V, replaced bool) {
 	s.mu.Lock()
 
 	if index, exists := s.table_Get(hash, key); exists {
@@ -138,6 +340,10 @@ func (s *ttlshard[K, V]) SetIfAbsent(hash uint32, key K, value V, ttl time.Durat
 			node.ttl = 0
 			node.expires = 0
 		}
+		if s.capacity > 0 {
+			s.used = s.used - node.charge + charge
+		}
+		node.charge = charge
 		replaced = true
 
 		s.mu.Unlock()
@@ -146,15 +352,29 @@ func (s *ttlshard[K, V]) SetIfAbsent(hash uint32, key K, value V, ttl time.Durat
 
 	s.stats_setcalls++
 
+	s.evictForCharge(charge)
+
 	// index := s.list_Back()
 	// node := &s.list[index]
-	index := s.list[0].prev
+	index, ok := s.nextEvictable()
+	if !ok {
+		// Every node in the shard is pinned via GetHandle: there is no slot
+		// to reclaim right now. Drop the Set rather than spin or corrupt a
+		// pinned handle's value.
+		s.mu.Unlock()
+		return
+	}
 	node := (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0])))
 	evictedValue := node.value
 	s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
+	if s.capacity > 0 {
+		s.used = s.used - node.charge + charge
+	}
 
 	node.key = key
 	node.value = value
+	node.charge = charge
+	node.ns = 0
 	if ttl > 0 {
 		node.ttl = uint32(ttl / time.Second)
 		node.expires = atomic.LoadUint32(&clock) + node.ttl
@@ -167,7 +387,7 @@ func (s *ttlshard[K, V]) SetIfAbsent(hash uint32, key K, value V, ttl time.Durat
 	return
 }
 
-func (s *ttlshard[K, V]) Set(hash uint32, key K, value V, ttl time.Duration) (prev V, replaced bool) {
+func (s *ttlshard[K, V]) Set(hash uint32, key K, value V, ttl time.Duration, charge uint32, ns uint32) (prev V, replaced bool) {
 	s.mu.Lock()
 
 	s.stats_setcalls++
@@ -182,6 +402,11 @@ func (s *ttlshard[K, V]) Set(hash uint32, key K, value V, ttl time.Duration) (pr
 			node.ttl = uint32(ttl / time.Second)
 			node.expires = atomic.LoadUint32(&clock) + node.ttl
 		}
+		if s.capacity > 0 {
+			s.used = s.used - node.charge + charge
+		}
+		node.charge = charge
+		node.ns = ns
 		prev = previousValue
 		replaced = true
 
@@ -189,17 +414,31 @@ func (s *ttlshard[K, V]) Set(hash uint32, key K, value V, ttl time.Duration) (pr
 		return
 	}
 
+	s.evictForCharge(charge)
+
 	// index := s.list_Back()
 	// node := &s.list[index]
-	index := s.list[0].prev
+	index, ok := s.nextEvictable()
+	if !ok {
+		// Every node in the shard is pinned via GetHandle: there is no slot
+		// to reclaim right now. Drop the Set rather than spin or corrupt a
+		// pinned handle's value.
+		s.mu.Unlock()
+		return
+	}
 	node := (*ttlnode[K, V])(unsafe.Add(unsafe.Pointer(&s.list[0]), uintptr(index)*unsafe.Sizeof(s.list[0])))
 	evictedValue := node.value
 	if key != node.key {
 		s.table_Delete(uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed)), node.key)
 	}
+	if s.capacity > 0 {
+		s.used = s.used - node.charge + charge
+	}
 
 	node.key = key
 	node.value = value
+	node.charge = charge
+	node.ns = ns
 	if ttl > 0 {
 		node.ttl = uint32(ttl / time.Second)
 		node.expires = atomic.LoadUint32(&clock) + node.ttl
@@ -219,13 +458,25 @@ func (s *ttlshard[K, V]) Delete(hash uint32, key K) (v V) {
 		node := &s.list[index]
 		value := node.value
 
-		if st, ok := any(value).(stoper); ok {
-			_ = st.Stop()
+		s.table_Delete(hash, key)
+		if s.capacity > 0 {
+			s.used -= node.charge
 		}
 
-		s.list_MoveToBack(index)
-		node.value = v
-		s.table_Delete(hash, key)
+		if node.refs > 0 {
+			// Pinned via GetHandle: defer Stop()/clobbering the value and
+			// recycling the slot to releaseHandle, same as Get's lazy-expiry
+			// branch and sweepExpired do.
+			node.pending = true
+		} else {
+			if st, ok := any(value).(stoper); ok {
+				_ = st.Stop()
+			}
+			s.list_MoveToBack(index)
+			var zero V
+			node.value = zero
+			node.charge = 0
+		}
 		v = value
 	}
 
@@ -243,6 +494,16 @@ func (s *ttlshard[K, V]) Len() (n uint32) {
 	return
 }
 
+// Bytes returns the shard's current charge usage. It is 0 for shards that
+// were not configured with a capacity.
+func (s *ttlshard[K, V]) Bytes() (used uint32) {
+	s.mu.Lock()
+	used = s.used
+	s.mu.Unlock()
+
+	return
+}
+
 func (s *ttlshard[K, V]) AppendKeys(dst []K, now uint32) []K {
 	s.mu.Lock()
 	for _, bucket := range s.table_buckets {
@@ -259,3 +520,189 @@ func (s *ttlshard[K, V]) AppendKeys(dst []K, now uint32) []K {
 
 	return dst
 }
+
+// LenNS returns the number of live entries tagged with namespace ns.
+func (s *ttlshard[K, V]) LenNS(ns uint32) (n uint32) {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*ttlbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		if s.list[b.index].ns == ns {
+			n++
+		}
+	}
+	s.mu.Unlock()
+
+	return
+}
+
+// AppendKeysNS is like AppendKeys but restricted to entries tagged with ns.
+func (s *ttlshard[K, V]) AppendKeysNS(dst []K, now uint32, ns uint32) []K {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*ttlbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		node := &s.list[b.index]
+		if node.ns != ns {
+			continue
+		}
+		if expires := node.expires; expires == 0 || now <= expires {
+			dst = append(dst, node.key)
+		}
+	}
+	s.mu.Unlock()
+
+	return dst
+}
+
+// EvictNS drops every entry tagged with namespace ns, invoking each value's
+// Stop() finalizer if it implements stoper, and returns the count evicted.
+func (s *ttlshard[K, V]) EvictNS(ns uint32) (n uint32) {
+	s.mu.Lock()
+	for _, bucket := range s.table_buckets {
+		b := (*ttlbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		node := &s.list[b.index]
+		if node.ns != ns {
+			continue
+		}
+		if s.capacity > 0 {
+			s.used -= node.charge
+		}
+		hash := uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed))
+		// entries set through a Namespace handle are keyed by the mixed hash
+		if i, ok := s.table_Get(mixNamespace(hash, ns), node.key); ok && i == b.index {
+			hash = mixNamespace(hash, ns)
+		}
+		s.table_Delete(hash, node.key)
+		if node.refs > 0 {
+			// pinned via GetHandle: releaseHandle finalizes it
+			node.pending = true
+		} else {
+			if st, ok := any(node.value).(stoper); ok {
+				_ = st.Stop()
+			}
+			s.list_MoveToBack(b.index)
+			var zero V
+			node.value = zero
+			node.charge = 0
+		}
+		n++
+	}
+	s.mu.Unlock()
+
+	return
+}
+
+// sweepExpired scans the shard for entries that expired before now, and
+// evicts them. Each expired entry is claimed (removed from the table, and
+// marked pending if pinned) before s.mu is dropped, so a concurrent Get on
+// the same key reliably misses instead of racing this sweep to finalize the
+// same value twice. Stop() finalizers are then invoked outside s.mu so a
+// slow or blocking finalizer (closing a file, a DB conn) cannot stall Get/Set
+// on this shard. Pinned entries are left for releaseHandle to finalize once
+// their last reference drops.
+func (s *ttlshard[K, V]) sweepExpired(now uint32) (expired uint32) {
+	type claimed struct {
+		index uint32
+		hash  uint32
+		key   K
+		value V
+	}
+
+	s.mu.Lock()
+	var claims []claimed
+	for _, bucket := range s.table_buckets {
+		b := (*ttlbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		node := &s.list[b.index]
+		if node.expires == 0 || now < node.expires {
+			continue
+		}
+
+		hash := uint32(s.table_hasher(noescape(unsafe.Pointer(&node.key)), s.table_seed))
+		s.table_Delete(hash, node.key)
+
+		if node.refs > 0 {
+			node.pending = true
+			continue
+		}
+
+		claims = append(claims, claimed{index: b.index, hash: hash, key: node.key, value: node.value})
+	}
+	s.mu.Unlock()
+
+	for _, c := range claims {
+		if st, ok := any(c.value).(stoper); ok {
+			_ = st.Stop()
+		}
+	}
+
+	s.mu.Lock()
+	for _, c := range claims {
+		node := &s.list[c.index]
+		// The slot may have been reused by a concurrent Set since we
+		// dropped the lock above: either by a different key landing on
+		// this index via nextEvictable, or by the same key being
+		// re-inserted in place. Either way node.key/the table will no
+		// longer agree with what we claimed, and finalizing now would
+		// clobber a live entry instead of the one we swept.
+		if node.key != c.key {
+			continue
+		}
+		if _, exists := s.table_Get(c.hash, c.key); exists {
+			continue
+		}
+		s.list_MoveToBack(c.index)
+		var zero V
+		node.value = zero
+		node.charge = 0
+		s.stats_expired++
+		expired++
+	}
+	s.mu.Unlock()
+
+	return
+}
+
+// drain evicts every live entry, invoking each value's Stop() finalizer, and
+// is used by TTLCache.Close to release resources held by cached entries.
+func (s *ttlshard[K, V]) drain() {
+	s.mu.Lock()
+	var values []V
+	for i, bucket := range s.table_buckets {
+		b := (*ttlbucket)(unsafe.Pointer(&bucket))
+		if b.index == 0 {
+			continue
+		}
+		node := &s.list[b.index]
+		if node.refs > 0 {
+			// pinned via GetHandle: releaseHandle finalizes it
+			node.pending = true
+		} else {
+			values = append(values, node.value)
+			var zero V
+			node.value = zero
+			node.charge = 0
+			s.list_MoveToBack(b.index)
+		}
+		s.table_buckets[i] = 0
+	}
+	s.table_length = 0
+	s.used = 0
+	s.mu.Unlock()
+
+	for _, value := range values {
+		if st, ok := any(value).(stoper); ok {
+			_ = st.Stop()
+		}
+	}
+}
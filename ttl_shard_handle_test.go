@@ -0,0 +1,83 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func ttlShardHash(s *ttlshard[string, int], key string) uint32 {
+	return uint32(s.table_hasher(noescape(unsafe.Pointer(&key)), s.table_seed))
+}
+
+func TestTTLShardHandlePinsAgainstEviction(t *testing.T) {
+	var s ttlshard[string, int]
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	ha := ttlShardHash(&s, "a")
+	s.Set(ha, "a", 1, 0, 0, 0)
+
+	index, value, ok := s.GetHandle(ha, "a")
+	if !ok || value != 1 {
+		t.Fatalf("GetHandle(a) = %v, %v, %v, want 1, true", value, ok, index)
+	}
+
+	// Insert enough other keys to cycle the whole ring; "a" must never be
+	// reused as a victim while its handle is outstanding.
+	s.Set(ttlShardHash(&s, "b"), "b", 2, 0, 0, 0)
+	s.Set(ttlShardHash(&s, "c"), "c", 3, 0, 0, 0)
+
+	if _, ok := s.table_Get(ha, "a"); !ok {
+		t.Fatalf("pinned key \"a\" was evicted while its handle was outstanding")
+	}
+
+	s.releaseHandle(index)
+}
+
+func TestTTLShardNextEvictableAllPinned(t *testing.T) {
+	var s ttlshard[string, int]
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	for _, key := range []string{"a", "b"} {
+		hash := ttlShardHash(&s, key)
+		s.Set(hash, key, 1, 0, 0, 0)
+		if _, _, ok := s.GetHandle(hash, key); !ok {
+			t.Fatalf("GetHandle(%s) failed", key)
+		}
+	}
+
+	if _, ok := s.nextEvictable(); ok {
+		t.Fatalf("nextEvictable() should report no victim when every node is pinned, not pick one")
+	}
+}
+
+func TestTTLShardDeleteRespectsPinnedHandle(t *testing.T) {
+	var s ttlshard[string, int]
+	s.Init(2, getRuntimeHasher[string](), 0)
+
+	ha := ttlShardHash(&s, "a")
+	s.Set(ha, "a", 1, 0, 0, 0)
+
+	index, _, ok := s.GetHandle(ha, "a")
+	if !ok {
+		t.Fatalf("GetHandle(a) failed")
+	}
+
+	if v := s.Delete(ha, "a"); v != 1 {
+		t.Fatalf("Delete(a) = %v, want 1", v)
+	}
+	if _, ok := s.table_Get(ha, "a"); ok {
+		t.Fatalf("deleted key \"a\" is still reachable via table_Get")
+	}
+
+	// The handle is still outstanding: the slot must not be recycled yet.
+	if s.list[index].value != 1 {
+		t.Fatalf("Delete finalized the pinned node's slot before its handle was released")
+	}
+
+	s.releaseHandle(index)
+	if s.list[index].value != 0 {
+		t.Fatalf("releaseHandle did not finalize a pending Delete once the last reference dropped")
+	}
+}
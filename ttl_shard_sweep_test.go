@@ -0,0 +1,83 @@
+// Copyright 2023-2024 Phus Lu. All rights reserved.
+
+package lru
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+type ttlStopRecorder struct {
+	stopped *bool
+}
+
+func (r ttlStopRecorder) Stop() error {
+	*r.stopped = true
+	return nil
+}
+
+func ttlStopRecorderShardHash(s *ttlshard[string, ttlStopRecorder], key string) uint32 {
+	return uint32(s.table_hasher(noescape(unsafe.Pointer(&key)), s.table_seed))
+}
+
+func TestTTLShardSweepExpiredFinalizesAndCounts(t *testing.T) {
+	var s ttlshard[string, ttlStopRecorder]
+	s.Init(4, getRuntimeHasher[string](), 0)
+
+	var stopped bool
+	hash := ttlStopRecorderShardHash(&s, "a")
+	s.Set(hash, "a", ttlStopRecorder{stopped: &stopped}, time.Nanosecond, 0, 0)
+
+	now := atomic.LoadUint32(&clock) + 1
+	if n := s.sweepExpired(now); n != 1 {
+		t.Fatalf("sweepExpired = %d, want 1", n)
+	}
+	if !stopped {
+		t.Fatalf("sweepExpired did not call Stop() on the expired value")
+	}
+	if _, ok := s.table_Get(hash, "a"); ok {
+		t.Fatalf("expired key \"a\" should no longer be reachable")
+	}
+	if s.stats_expired != 1 {
+		t.Fatalf("stats_expired = %d, want 1", s.stats_expired)
+	}
+
+	// A second sweep at the same watermark must be a no-op: nothing left to
+	// expire, and the already-finalized slot must not be double-counted.
+	if n := s.sweepExpired(now); n != 0 {
+		t.Fatalf("second sweepExpired = %d, want 0", n)
+	}
+}
+
+func TestTTLCacheCloseStopsJanitorAndDrains(t *testing.T) {
+	c := NewTTLCache[string, ttlStopRecorder](16, WithJanitor(time.Millisecond))
+
+	var stopped bool
+	c.Set("a", ttlStopRecorder{stopped: &stopped}, time.Hour)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() = %v, want nil", err)
+	}
+	if !stopped {
+		t.Fatalf("Close() should finalize every live entry via Stop()")
+	}
+	if n := c.Len(); n != 0 {
+		t.Fatalf("Len() after Close() = %d, want 0", n)
+	}
+}
+
+func TestTTLCacheStatsExpired(t *testing.T) {
+	c := NewTTLCache[string, int](16)
+
+	c.Set("a", 1, time.Nanosecond)
+	now := atomic.LoadUint32(&clock) + 1
+	for i := uint32(0); i <= c.mask; i++ {
+		c.shards[i].sweepExpired(now)
+	}
+
+	if got := c.Stats().Expired; got != 1 {
+		t.Fatalf("Stats().Expired = %d, want 1", got)
+	}
+}